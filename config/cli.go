@@ -4,6 +4,41 @@ import "github.com/alecthomas/kong"
 
 type CLI struct {
 	RepoPath  string           `short:"r" name:"repo" help:"Path of the restic repository" env:"RESTIC_REPOSITORY" required:""`
-	MountPath string           `short:"m" name:"mount" help:"Path of the restic mount point" env:"RESTIC_MOUNTPOINT" required:""`
+	MountPath string           `short:"m" name:"mount" help:"Path of the restic mount point" env:"RESTIC_MOUNTPOINT"`
+	Source    string           `short:"s" name:"source" help:"Where to read snapshot trees from: auto, mount or ls. Auto uses the mount point if one is given" enum:"auto,mount,ls" default:"auto"`
 	Version   kong.VersionFlag `short:"v" name:"version" help:"Show app version"`
+
+	Moves        string `name:"moves" help:"Pair removed and added entries with the same size and files as moves: off, dir (within a directory) or tree (anywhere)" enum:"off,dir,tree" default:"off"`
+	ExportFormat string `name:"export-format" help:"Format of the files exported from the compare view: json, json-tree, csv or ndjson" enum:"json,json-tree,csv,ndjson" default:"json"`
+
+	Tui    TuiCmd    `cmd:"" default:"withargs" help:"Browse and compare snapshots interactively (default)"`
+	Diff   DiffCmd   `cmd:"" help:"Print the size diff between two snapshots"`
+	Growth GrowthCmd `cmd:"" help:"Rank the paths that grew the most over a range of snapshots"`
+}
+
+type TuiCmd struct {
+	HideUnchanged bool   `name:"hide-unchanged" help:"Hide entries without changes in the compare view"`
+	OnlyModified  bool   `name:"only-modified" help:"Show only the entries whose content changed in the compare view"`
+	Threshold     string `short:"t" name:"threshold" help:"Hide entries with a smaller diff, as a size (1MiB) or a percentage of the directory (0.5%)" default:"0"`
+	ExcludeFile   string `name:"exclude-file" help:"restic exclude file the rows marked in the compare view are written to" default:"${exclude_file}" type:"path"`
+	New           string `name:"new" help:"Compare this snapshot without the selector: ID, 'latest' or 'latest~N' (default latest)"`
+	Old           string `name:"old" help:"Compare with this snapshot without the selector: ID, 'previous', 'host-previous' or an age like '1w' before --new (default previous)"`
+	Force         bool   `name:"force" help:"Compare snapshots from different hosts or of different paths"`
+}
+
+type DiffCmd struct {
+	New    string `arg:"" name:"new" help:"Newer snapshot: ID, 'latest' or 'latest~N'"`
+	Old    string `arg:"" name:"old" help:"Older snapshot: ID, 'latest', 'latest~N', 'previous', 'host-previous' or an age like '1w' before the newer one"`
+	Depth  int    `short:"d" name:"depth" help:"Print paths up to this depth (0 for no limit)" default:"1"`
+	Top    int    `short:"n" name:"top" help:"Print only the N largest diffs (0 for no limit)" default:"0"`
+	Format string `short:"f" name:"format" help:"Output format: text, json, json-tree, csv or ndjson" enum:"text,json,json-tree,csv,ndjson" default:"text"`
+	Output string `short:"o" name:"output" help:"Write the diff to a file instead of the standard output" type:"path"`
+	Force  bool   `name:"force" help:"Compare snapshots from different hosts or of different paths"`
+}
+
+type GrowthCmd struct {
+	Since string `name:"since" help:"Age of the oldest snapshot of the range, e.g. 30d, 2w or 12h" default:"30d"`
+	Host  string `name:"host" help:"Only compare the snapshots of this host"`
+	Depth int    `short:"d" name:"depth" help:"Rank paths up to this depth (0 for no limit)" default:"3"`
+	Top   int    `short:"n" name:"top" help:"Print only the N paths that grew the most (0 for no limit)" default:"20"`
 }
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"gestic/config"
+	"gestic/models/compare"
+	"gestic/restic"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/dustin/go-humanize"
+)
+
+// runDiff prints the diff tree of two snapshots
+func runDiff(w io.Writer, cmd config.DiffCmd, moves compare.MoveScope, source restic.SnapshotSource, snapshots []restic.Snapshot) error {
+	newer, older, err := restic.FindPair(snapshots, cmd.New, cmd.Old)
+	if err != nil {
+		return err
+	}
+
+	if err := restic.CheckComparable(newer, older); err != nil && !cmd.Force {
+		return fmt.Errorf("%w, use --force to compare them anyway", err)
+	}
+
+	newTree, err := source.Tree(context.Background(), newer, nil)
+	if err != nil {
+		return fmt.Errorf("can't read snapshot %s: %w", newer.ShortId, err)
+	}
+	oldTree, err := source.Tree(context.Background(), older, nil)
+	if err != nil {
+		return fmt.Errorf("can't read snapshot %s: %w", older.ShortId, err)
+	}
+
+	tree := compare.BuildTree(newTree, oldTree)
+	compare.DetectMoves(tree, moves)
+	records := compare.CreateRecords(tree, cmd.Depth)
+	// The top entries can come from any level, so they lose the nesting
+	if cmd.Top > 0 {
+		records = compare.FlattenRecords(records)
+		sort.SliceStable(records, func(i, j int) bool {
+			return records[i].Diff > records[j].Diff
+		})
+		if len(records) > cmd.Top {
+			records = records[:cmd.Top]
+		}
+	}
+
+	if cmd.Output != "" {
+		f, err := os.Create(cmd.Output)
+		if err != nil {
+			return fmt.Errorf("can't create output file: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if cmd.Format != "text" {
+		_, err := compare.Export(w, cmd.Format, records)
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	_, _ = fmt.Fprintf(tw, "NEW (%s)\tOLD (%s)\tDIFF\tFILES\t PATH\n", newer.ShortId, older.ShortId)
+	for _, r := range compare.FlattenRecords(records) {
+		newSize := humanize.Bytes(uint64(r.NewSize))
+		if r.Status == compare.StatusRemoved {
+			newSize = "-"
+		}
+		oldSize := humanize.Bytes(uint64(r.OldSize))
+		if r.Status == compare.StatusAdded {
+			oldSize = "-"
+		}
+		sign := "+"
+		if r.Diff < 0 {
+			sign = "-"
+		}
+		path := r.Path
+		switch r.Status {
+		case compare.StatusMoved:
+			path = fmt.Sprintf("%s (moved from %s)", r.Path, r.MovedFrom)
+		case compare.StatusModified:
+			path = fmt.Sprintf("%s (modified)", r.Path)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s%s\t%+d\t %s\n",
+			newSize,
+			oldSize,
+			sign, humanize.Bytes(uint64(max(r.Diff, -r.Diff))),
+			r.FilesDiff,
+			path,
+		)
+	}
+	return tw.Flush()
+}
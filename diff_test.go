@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"gestic/config"
+	"gestic/models/compare"
+	"gestic/restic"
+	"gestic/restic/restictest"
+)
+
+func TestRunDiff(t *testing.T) {
+	root := t.TempDir()
+	restictest.WriteFiles(t, root, map[string]string{
+		"2024-01-01T00:00:00Z/docs/a.txt": "aaaa",
+		"2024-01-01T00:00:00Z/old.txt":    "old",
+		"2024-01-02T00:00:00Z/docs/a.txt": "aaaaaaaa",
+		"2024-01-02T00:00:00Z/docs/b.txt": "b",
+		"2024-01-02T00:00:00Z/new.txt":    "old",
+	})
+	source := restic.DirSource{Root: root}
+	snapshots, err := source.Snapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		cmd   config.DiffCmd
+		moves compare.MoveScope
+		want  []string // Lines of the output, without the spaces
+		err   string
+	}{
+		{
+			name: "text",
+			cmd:  config.DiffCmd{New: "latest", Old: "previous", Depth: 1, Format: "text"},
+			want: []string{"NEW(2024-01-)OLD(2024-01-)DIFFFILESPATH", "9B4B+5B+1/docs", "3B-+3B+1/new.txt", "-3B-3B-1/old.txt"},
+		},
+		{
+			name:  "moves and top",
+			cmd:   config.DiffCmd{New: "latest", Old: "previous", Depth: 0, Top: 2, Format: "text"},
+			moves: compare.MovesDir,
+			want:  []string{"NEW(2024-01-)OLD(2024-01-)DIFFFILESPATH", "9B4B+5B+1/docs", "8B4B+4B+0/docs/a.txt"},
+		},
+		{
+			name: "csv",
+			cmd:  config.DiffCmd{New: "latest", Old: "2024-01-01", Depth: 1, Format: "csv"},
+			want: []string{"path,new_size,old_size,diff,new_files,old_files,files_diff,status,moved_from", "/docs,9,4,5,2,1,1,changed,", "/new.txt,3,0,3,1,0,1,added,", "/old.txt,0,3,-3,0,1,-1,removed,"},
+		},
+		{
+			name: "same snapshot",
+			cmd:  config.DiffCmd{New: "latest", Old: "2024-01-02", Format: "text"},
+			err:  "the same snapshot",
+		},
+		{
+			name: "reversed",
+			cmd:  config.DiffCmd{New: "2024-01-01", Old: "latest", Format: "text"},
+			err:  "swap them",
+		},
+		{
+			name: "unknown",
+			cmd:  config.DiffCmd{New: "latest", Old: "2023", Format: "text"},
+			err:  "no snapshot matches",
+		},
+	}
+	for _, tt := range tests {
+		var output strings.Builder
+		err := runDiff(&output, tt.cmd, tt.moves, source, snapshots)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error = %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		lines := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+		for i := range lines {
+			lines[i] = strings.ReplaceAll(lines[i], " ", "")
+		}
+		if strings.Join(lines, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s: output =\n%s\nwant\n%s", tt.name, strings.Join(lines, "\n"), strings.Join(tt.want, "\n"))
+		}
+	}
+}
+
+func TestRunDiffHosts(t *testing.T) {
+	root := t.TempDir()
+	restictest.WriteFiles(t, root, map[string]string{"2024-01-01T00:00:00Z/a": "a", "2024-01-02T00:00:00Z/a": "aa"})
+	source := restic.DirSource{Root: root}
+	snapshots, err := source.Snapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshots[0].Hostname, snapshots[1].Hostname = "h1", "h2"
+
+	cmd := config.DiffCmd{New: "latest", Old: "previous", Depth: 1, Format: "text"}
+	if err := runDiff(&strings.Builder{}, cmd, compare.MovesOff, source, snapshots); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("error = %v, want a mismatch asking for --force", err)
+	}
+	cmd.Force = true
+	if err := runDiff(&strings.Builder{}, cmd, compare.MovesOff, source, snapshots); err != nil {
+		t.Errorf("error with --force: %v", err)
+	}
+}
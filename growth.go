@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"gestic/config"
+	"gestic/models/compare"
+	"gestic/models/growth"
+	"gestic/restic"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// runGrowth prints the paths that grew the most over the snapshots of
+// the range
+func runGrowth(w io.Writer, cmd config.GrowthCmd, moves compare.MoveScope, source restic.SnapshotSource, snapshots []restic.Snapshot) error {
+	since, err := restic.ParseAge(cmd.Since)
+	if err != nil {
+		return fmt.Errorf("--since: %w", err)
+	}
+	snapshots = growth.Range(snapshots, time.Now().Add(-since), cmd.Host)
+
+	report, err := growth.Compute(context.Background(), source, snapshots, cmd.Depth, moves, func(done, total int) {
+		_, _ = fmt.Fprintf(os.Stderr, "\rReading snapshots... %d/%d", done, total)
+	})
+	_, _ = fmt.Fprintf(os.Stderr, "\r\033[K")
+	if err != nil {
+		return err
+	}
+
+	entries := report.Entries
+	if cmd.Top > 0 && len(entries) > cmd.Top {
+		entries = entries[:cmd.Top]
+	}
+
+	first, last := report.Snapshots[0], report.Snapshots[len(report.Snapshots)-1]
+	_, _ = fmt.Fprintf(w, "%d snapshots from %s (%s) to %s (%s)\n\n",
+		len(report.Snapshots),
+		first.ShortId, first.Date.Format("2006-01-02 15:04"),
+		last.ShortId, last.Date.Format("2006-01-02 15:04"))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "GROWTH\tLARGEST JUMP\tBETWEEN\tPATH\n")
+	for _, e := range entries {
+		largest := e.Largest()
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n",
+			growth.SignedBytes(e.Growth),
+			growth.SignedBytes(largest.Diff),
+			largest.Pair,
+			report.Name(e),
+		)
+	}
+	return tw.Flush()
+}
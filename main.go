@@ -1,8 +1,11 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"gestic/config"
+	"gestic/models/compare"
+	"gestic/models/compare/exclude"
 	"gestic/models/selector"
 	"gestic/restic"
 	"os"
@@ -23,28 +26,100 @@ func main() {
 		kong.Name("gestic"),
 		kong.Description("A diff tool for restic snapshots."),
 		kong.Vars{
-			"version": fmt.Sprintf("gestic %s (%s)", version, commit),
+			"version":      fmt.Sprintf("gestic %s (%s)", version, commit),
+			"exclude_file": exclude.DefaultFile,
 		},
 	)
 
-	// It returns error if no subcommand was provided
-	// We don't use subcommands and are ignoring errors
-	err := ctx.Run()
-	//if err != nil {
-	//	ctx.FatalIfErrorf(err)
-	//}
+	threshold, err := compare.ParseThreshold(cli.Tui.Threshold)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: --threshold: %v\n", err)
+		os.Exit(1)
+	}
+	options := compare.Options{
+		ExportFormat:  cli.ExportFormat,
+		Moves:         compare.MoveScope(cli.Moves),
+		HideUnchanged: cli.Tui.HideUnchanged,
+		OnlyModified:  cli.Tui.OnlyModified,
+		Threshold:     threshold,
+		ExcludeFile:   cli.Tui.ExcludeFile,
+	}
 
-	snapshots, err := restic.GetSnapshots(cli.RepoPath, cli.MountPath)
+	source := newSource(cli)
+	snapshots, err := source.Snapshots()
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error: cannot get snapshots: %v\n\n", err)
-		_, _ = fmt.Fprintf(os.Stderr, "Did you mount the repository?\n")
-		_, _ = fmt.Fprintf(os.Stderr, "Run 'man restic mount' for more information.")
+		printSnapshotsHelp(err)
 		os.Exit(1)
 	}
 
-	p := tea.NewProgram(
-		selector.InitialModel(snapshots),
-	)
+	switch ctx.Command() {
+	case "diff <new> <old>":
+		if err := runDiff(os.Stdout, cli.Diff, options.Moves, source, snapshots); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "growth":
+		if err := runGrowth(os.Stdout, cli.Growth, options.Moves, source, snapshots); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	default:
+		model, err := initialModel(cli.Tui, source, snapshots, options)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		runTui(model)
+	}
+}
+
+// newSource returns the snapshot source selected by the command line
+func newSource(cli config.CLI) restic.SnapshotSource {
+	useMount := cli.Source == "mount" || (cli.Source == "auto" && cli.MountPath != "")
+	if useMount && cli.MountPath == "" {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: --source=mount requires --mount\n")
+		os.Exit(1)
+	}
+	if !useMount && !hasPasswordEnv() {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: reading snapshots without a mount point requires the restic password in the environment\n\n")
+		_, _ = fmt.Fprintf(os.Stderr, "Set RESTIC_PASSWORD, RESTIC_PASSWORD_FILE or RESTIC_PASSWORD_COMMAND, or use --mount.\n")
+		os.Exit(1)
+	}
+
+	if useMount {
+		return restic.MountSource{RepoPath: cli.RepoPath, MountPath: cli.MountPath}
+	}
+	return restic.LsSource{RepoPath: cli.RepoPath}
+}
+
+// initialModel returns the snapshot selector, already comparing the
+// snapshots given with --new and --old if any
+func initialModel(cmd config.TuiCmd, source restic.SnapshotSource, snapshots []restic.Snapshot, options compare.Options) (tea.Model, error) {
+	model := selector.InitialModel(source, snapshots, options)
+	if cmd.New == "" && cmd.Old == "" {
+		return model, nil
+	}
+
+	newRef, oldRef := cmd.New, cmd.Old
+	if newRef == "" {
+		newRef = "latest"
+	}
+	if oldRef == "" {
+		oldRef = restic.RefPrevious
+	}
+	newer, older, err := restic.FindPair(snapshots, newRef, oldRef)
+	if err != nil {
+		return nil, err
+	}
+	if err := restic.CheckComparable(newer, older); err != nil && !cmd.Force {
+		return nil, fmt.Errorf("%w, use --force to compare them anyway", err)
+	}
+	return model.Preselect(newer, older), nil
+}
+
+func runTui(model tea.Model) {
+	p := tea.NewProgram(model)
 
 	// Redirects the debug to a local file
 	debugFile := "/dev/null"
@@ -63,3 +138,36 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// printSnapshotsHelp suggests a fix for errors returned by restic.GetSnapshots
+func printSnapshotsHelp(err error) {
+	var unparseable *restic.UnparseableOutputError
+	var withoutFolder *restic.SnapshotWithoutFolderError
+	var notPresent *restic.MountNotPresentError
+
+	switch {
+	case errors.Is(err, restic.ErrNoSnapshots):
+		_, _ = fmt.Fprintf(os.Stderr, "The repository has no snapshots to compare.\n")
+	case errors.As(err, &unparseable):
+		_, _ = fmt.Fprintf(os.Stderr, "Your restic version may not be supported.\n")
+		_, _ = fmt.Fprintf(os.Stderr, "Check if 'restic %s --json' works in your terminal.\n", unparseable.Command)
+	case errors.As(err, &withoutFolder):
+		_, _ = fmt.Fprintf(os.Stderr, "The mount point is older than the snapshots.\n")
+		_, _ = fmt.Fprintf(os.Stderr, "Restart 'restic mount' to see the new snapshots.\n")
+	case errors.As(err, &notPresent):
+		_, _ = fmt.Fprintf(os.Stderr, "Did you mount the repository?\n")
+		_, _ = fmt.Fprintf(os.Stderr, "Run 'man restic mount' for more information.\n")
+	default:
+		_, _ = fmt.Fprintf(os.Stderr, "Check if the repository path and password are correct.\n")
+	}
+}
+
+// hasPasswordEnv checks if restic can get the password without a terminal
+func hasPasswordEnv() bool {
+	for _, name := range []string{"RESTIC_PASSWORD", "RESTIC_PASSWORD_FILE", "RESTIC_PASSWORD_COMMAND"} {
+		if os.Getenv(name) != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package compare
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// crumbs returns the nodes from the root to the current one
+func (m *Model) crumbs() []*Node {
+	return append(m.node.Ancestors(), m.node)
+}
+
+// breadcrumbView renders the path of the current directory. Each
+// segment has the diff of its directory.
+func (m *Model) breadcrumbView() string {
+	var segments []string
+	for i, n := range m.crumbs() {
+		name := n.Name
+		if n.Parent != nil {
+			name = strings.TrimPrefix(n.PathReadable(), "/")
+		}
+		segment := fmt.Sprintf("%s (%s)", name, n.DiffReadable())
+		if m.selectingCrumb && i == m.crumbCursor {
+			segment = crumbSelectedStyle.Render(segment)
+		}
+		segments = append(segments, segment)
+	}
+	return crumbStyle.Render(strings.Join(segments, " › "))
+}
+
+// updateBreadcrumb handles the keys while choosing a segment of the breadcrumb
+func (m *Model) updateBreadcrumb(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keyMap.Confirm):
+		m.selectingCrumb = false
+		return m.goBackTo(m.crumbs()[m.crumbCursor])
+	case key.Matches(msg, m.keyMap.Cancel, m.keyMap.Breadcrumb):
+		m.selectingCrumb = false
+	case key.Matches(msg, m.keyMap.CrumbLeft):
+		m.crumbCursor = max(m.crumbCursor-1, 0)
+	case key.Matches(msg, m.keyMap.CrumbRight):
+		m.crumbCursor = min(m.crumbCursor+1, len(m.crumbs())-1)
+	}
+	return m, nil
+}
+
+// goBackTo returns the model of the prevModel chain showing target,
+// with the view options of the current one but its own sort
+func (m *Model) goBackTo(target *Node) (tea.Model, tea.Cmd) {
+	m.saveState()
+	prev, ok := FindPrev(m, target)
+	if !ok {
+		return m, nil
+	}
+
+	// Keep the view options chosen in the subdirectory, except the
+	// sort, which is saved per directory
+	options := m.options
+	options.Sort = prev.options.Sort
+	prev.setOptions(options)
+	return prev, ResizeCmd(m.width, m.height)
+}
+
+// DirModel is a model of a directory, opened from the model of one of
+// its ancestors
+type DirModel[N comparable] interface {
+	comparable
+	Dir() N
+	Prev() tea.Model
+}
+
+// FindPrev returns the model of the prevModel chain of m showing dir,
+// false if it's m or there is none
+func FindPrev[M DirModel[N], N comparable](m M, dir N) (M, bool) {
+	prev := m
+	for prev.Dir() != dir {
+		next, ok := prev.Prev().(M)
+		if !ok {
+			return m, false
+		}
+		prev = next
+	}
+	return prev, prev != m
+}
+
+// ResizeCmd sends the window size to a model shown again, which missed
+// its changes while it was hidden
+func ResizeCmd(width, height int) tea.Cmd {
+	return func() tea.Msg {
+		return tea.WindowSizeMsg{Width: width, Height: height}
+	}
+}
+
+func (m *Model) Dir() *Node {
+	return m.node
+}
+
+func (m *Model) Prev() tea.Model {
+	return m.prevModel
+}
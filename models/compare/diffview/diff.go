@@ -0,0 +1,158 @@
+package diffview
+
+// Kind of a diff line
+type Kind int
+
+const (
+	Equal Kind = iota
+	Deleted
+	Inserted
+)
+
+// Line is a line of the diff. The numbers start at 1 and are 0 if the
+// line is not in that file.
+type Line struct {
+	Kind  Kind
+	OldNo int
+	NewNo int
+	Text  string
+}
+
+// diffLines returns the lines of a and b as an edit script from a to b.
+// The lines are numbered from oldStart and newStart.
+func diffLines(a, b []string, oldStart, newStart int) []Line {
+	var lines []Line
+	equal := func(i, j int) {
+		lines = append(lines, Line{Kind: Equal, OldNo: oldStart + i, NewNo: newStart + j, Text: b[j]})
+	}
+
+	// The common prefix and suffix don't need the table
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		equal(prefix, prefix)
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	// Longest common subsequence of the middle lines
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	n, m := len(midA), len(midB)
+	lcs := make([][]int32, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if midA[i] == midB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && midA[i] == midB[j]:
+			equal(prefix+i, prefix+j)
+			i++
+			j++
+		case i < n && (j == m || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, Line{Kind: Deleted, OldNo: oldStart + prefix + i, Text: midA[i]})
+			i++
+		default:
+			lines = append(lines, Line{Kind: Inserted, NewNo: newStart + prefix + j, Text: midB[j]})
+			j++
+		}
+	}
+
+	for k := suffix; k > 0; k-- {
+		equal(len(a)-k, len(b)-k)
+	}
+	return lines
+}
+
+// hunk is a group of changed lines with their context
+type hunk struct {
+	lines    []Line
+	oldStart int
+	oldCount int
+	newStart int
+	newCount int
+}
+
+// newHunk returns the hunk of lines
+func newHunk(lines []Line) hunk {
+	h := hunk{lines: lines}
+	for _, l := range h.lines {
+		if l.Kind != Inserted {
+			if h.oldStart == 0 {
+				h.oldStart = l.OldNo
+			}
+			h.oldCount++
+		}
+		if l.Kind != Deleted {
+			if h.newStart == 0 {
+				h.newStart = l.NewNo
+			}
+			h.newCount++
+		}
+	}
+	return h
+}
+
+// hunker groups the changed lines with up to context equal lines around
+// them as the lines are added. Changes closer than twice the context
+// share a hunk.
+type hunker struct {
+	context int
+	lines   []Line
+	closed  []hunk // Hunks that later lines can't extend
+	start   int    // First line of the open hunk, -1 if none
+	last    int    // Last change of the open hunk
+	prevEnd int    // End of the last closed hunk
+	changed bool
+}
+
+func newHunker(context int) hunker {
+	return hunker{context: context, start: -1}
+}
+
+// add appends lines, closing the open hunk once the equal lines after
+// its last change are too many to join the next change
+func (h *hunker) add(lines []Line) {
+	for _, l := range lines {
+		i := len(h.lines)
+		h.lines = append(h.lines, l)
+		if l.Kind == Equal {
+			if h.start >= 0 && i-h.last > 2*h.context {
+				h.close()
+			}
+			continue
+		}
+		h.changed = true
+		if h.start < 0 {
+			h.start = max(i-h.context, h.prevEnd)
+		}
+		h.last = i
+	}
+}
+
+func (h *hunker) close() {
+	end := min(h.last+1+h.context, len(h.lines))
+	h.closed = append(h.closed, newHunk(h.lines[h.start:end]))
+	h.start, h.prevEnd = -1, end
+}
+
+// open returns the hunk still extended by the added lines, if any
+func (h *hunker) open() (hunk, bool) {
+	if h.start < 0 {
+		return hunk{}, false
+	}
+	end := min(h.last+1+h.context, len(h.lines))
+	return newHunk(h.lines[h.start:end]), true
+}
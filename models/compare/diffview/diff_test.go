@@ -0,0 +1,166 @@
+package diffview
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"gestic/restic"
+)
+
+// script returns the kinds of lines as a string, "=" for equal lines,
+// "-" for deleted ones and "+" for inserted ones
+func script(lines []Line) string {
+	var output strings.Builder
+	for _, l := range lines {
+		output.WriteString([]string{Equal: "=", Deleted: "-", Inserted: "+"}[l.Kind])
+	}
+	return output.String()
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{"", "", ""},
+		{"a b c", "a b c", "==="},
+		{"a b c", "a x c", "=-+="},
+		{"a b c", "a c", "=-="},
+		{"a c", "a b c", "=+="},
+		{"a b", "c d", "--++"},
+		{"a b c d", "b c d a", "-===+"},
+	}
+	for _, tt := range tests {
+		lines := diffLines(strings.Fields(tt.a), strings.Fields(tt.b), 1, 1)
+		if got := script(lines); got != tt.want {
+			t.Errorf("diffLines(%q, %q) = %s, want %s", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	lines := diffLines([]string{"a", "b"}, []string{"a", "c"}, 10, 20)
+	want := []Line{
+		{Kind: Equal, OldNo: 10, NewNo: 20, Text: "a"},
+		{Kind: Deleted, OldNo: 11, Text: "b"},
+		{Kind: Inserted, NewNo: 21, Text: "c"},
+	}
+	for i := range want {
+		if i >= len(lines) || lines[i] != want[i] {
+			t.Fatalf("lines = %+v, want %+v", lines, want)
+		}
+	}
+}
+
+func TestHunker(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string // Scripts of the lines added at a time
+		hunks  []string // Scripts of the closed hunks, then the open one
+	}{
+		{"equal", []string{"======"}, nil},
+		{"one change", []string{"=====-+====="}, []string{"==-+=="}},
+		{"close changes", []string{"===-==+==="}, []string{"==-==+=="}},
+		{"far changes", []string{"=-=====+="}, []string{"=-==", "==+="}},
+		{"split chunks", []string{"==", "=-", "===", "==+", "="}, []string{"==-==", "==+="}},
+	}
+	for _, tt := range tests {
+		h := newHunker(2)
+		n := 0
+		for _, chunk := range tt.chunks {
+			var lines []Line
+			for _, c := range chunk {
+				n++
+				kind := map[rune]Kind{'=': Equal, '-': Deleted, '+': Inserted}[c]
+				lines = append(lines, Line{Kind: kind, OldNo: n, NewNo: n})
+			}
+			h.add(lines)
+		}
+		var got []string
+		for _, hk := range h.closed {
+			got = append(got, script(hk.lines))
+		}
+		if hk, ok := h.open(); ok {
+			got = append(got, script(hk.lines))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.hunks) || h.changed != (len(tt.hunks) > 0) {
+			t.Errorf("%s: hunks = %v, want %v", tt.name, got, tt.hunks)
+		}
+	}
+}
+
+// numbered returns the lines from-to, both included
+func numbered(from, to int) []string {
+	var lines []string
+	for i := from; i <= to; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	return lines
+}
+
+func TestAddChunk(t *testing.T) {
+	file := numbered(1, 3000)
+	tests := []struct {
+		name              string
+		old, new          []string
+		deleted, inserted int
+	}{
+		{"same", file, file, 0, 0},
+		{"small change", file, append(append(numbered(1, 1499), "changed"), numbered(1501, 3000)...), 1, 1},
+		{"long insertion", file, append(numbered(5001, 6200), file...), 0, 1200},
+		{"long deletion", append(numbered(5001, 6200), file...), file, 1200, 0},
+		{"insertion in the middle", file, append(append(numbered(1, 900), numbered(5001, 7500)...), numbered(901, 3000)...), 0, 2500},
+	}
+	for _, tt := range tests {
+		m := InitialModel(nil, 80, 24, restic.SnapshotsMetadata{}, "file", &restic.DirData{}, &restic.DirData{})
+		m.oldStream = newStream(io.NopCloser(strings.NewReader(strings.Join(tt.old, "\n"))))
+		m.newStream = newStream(io.NopCloser(strings.NewReader(strings.Join(tt.new, "\n"))))
+		for !m.done {
+			oldLines, _ := m.oldStream.readLines(chunkLines)
+			newLines, _ := m.newStream.readLines(chunkLines)
+			m.addChunk(oldLines, newLines)
+		}
+
+		var deleted, inserted int
+		for _, l := range m.hunks.lines {
+			switch l.Kind {
+			case Deleted:
+				deleted++
+			case Inserted:
+				inserted++
+			}
+		}
+		if deleted != tt.deleted || inserted != tt.inserted {
+			t.Errorf("%s: deleted %d and inserted %d lines, want %d and %d", tt.name, deleted, inserted, tt.deleted, tt.inserted)
+		}
+		if m.oldCount != len(tt.old) || m.newCount != len(tt.new) || m.err != nil {
+			t.Errorf("%s: compared %d / %d lines (%v), want %d / %d", tt.name, m.oldCount, m.newCount, m.err, len(tt.old), len(tt.new))
+		}
+	}
+}
+
+// failingCloser is a file whose reader reports an error when closed,
+// like restic dump
+type failingCloser struct {
+	io.Reader
+}
+
+func (failingCloser) Close() error {
+	return fmt.Errorf("restic dump failed")
+}
+
+func TestAddChunkCloseError(t *testing.T) {
+	m := InitialModel(nil, 80, 24, restic.SnapshotsMetadata{}, "file", &restic.DirData{}, &restic.DirData{})
+	m.oldStream = newStream(failingCloser{strings.NewReader("")})
+	m.newStream = newStream(io.NopCloser(strings.NewReader("")))
+	oldLines, _ := m.oldStream.readLines(chunkLines)
+	newLines, _ := m.newStream.readLines(chunkLines)
+	m.addChunk(oldLines, newLines)
+
+	if !m.done || m.err == nil {
+		t.Errorf("done = %v, err = %v, want the error of the closed file", m.done, m.err)
+	}
+	if view := m.View(); !strings.Contains(view, "restic dump failed") {
+		t.Errorf("the view doesn't show the error:\n%s", view)
+	}
+}
@@ -0,0 +1,44 @@
+package diffview
+
+import "github.com/charmbracelet/bubbles/key"
+
+type keymap struct {
+	Layout key.Binding
+	More   key.Binding
+	Back   key.Binding
+	Quit   key.Binding
+}
+
+func (k keymap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Layout, k.More, k.Back}
+}
+
+func (k keymap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Layout, k.More},
+		{k.Back, k.Quit},
+	}
+}
+
+// DefaultKeyMap returns the keys of the diff pane. The viewport keys
+// scroll the diff.
+func DefaultKeyMap() keymap {
+	return keymap{
+		Layout: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", "Switch layout"),
+		),
+		More: key.NewBinding(
+			key.WithKeys("G"),
+			key.WithHelp("G", "Load all"),
+		),
+		Back: key.NewBinding(
+			key.WithKeys("esc", "q"),
+			key.WithHelp("esc/q", "Back"),
+		),
+		Quit: key.NewBinding(
+			key.WithKeys("ctrl+c"),
+			key.WithHelp("ctrl+c", "quit"),
+		),
+	}
+}
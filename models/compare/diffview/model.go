@@ -0,0 +1,341 @@
+package diffview
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gestic/models"
+	"gestic/restic"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dustin/go-humanize"
+)
+
+// chunkLines is the number of lines read from each file at a time
+const chunkLines = 1000
+
+// maxPendingLines is the number of lines read from a file without
+// finding equal lines after which they are diffed anyway
+const maxPendingLines = 3 * chunkLines
+
+// LargeFileSize is the size from which the pane warns that the files
+// are compared as they are scrolled
+const LargeFileSize = 10 * 1000 * 1000
+
+// Model is a scrollable diff of a file in two snapshots
+type Model struct {
+	prevModel tea.Model
+	help      help.Model
+	keyMap    keymap
+	width     int
+	height    int
+
+	metadata restic.SnapshotsMetadata
+	name     string
+	newEntry *restic.DirData
+	oldEntry *restic.DirData
+
+	layout      Layout
+	highlighter highlighter
+	viewport    viewport.Model
+
+	oldStream *stream
+	newStream *stream
+	binary    bool
+	mimeOld   string
+	mimeNew   string
+	loading   bool
+	loadAll   bool // Keep loading until the end of the files
+	done      bool
+	err       error
+
+	hunks      hunker
+	pendingOld []string // Lines read but not diffed yet
+	pendingNew []string
+	oldCount   int // Lines of each file in lines
+	newCount   int
+
+	rendered      strings.Builder // Closed hunks in the layout
+	renderedHunks int
+}
+
+type openedMsg struct {
+	oldStream *stream
+	newStream *stream
+	err       error
+}
+
+type chunkMsg struct {
+	oldLines []string
+	newLines []string
+	err      error
+}
+
+// InitialModel returns the diff of a file found in both snapshots of metadata
+func InitialModel(prevModel tea.Model, width, height int, metadata restic.SnapshotsMetadata, name string, newEntry, oldEntry *restic.DirData) *Model {
+	m := &Model{
+		prevModel:   prevModel,
+		help:        help.New(),
+		keyMap:      DefaultKeyMap(),
+		width:       width,
+		height:      height,
+		metadata:    metadata,
+		name:        name,
+		newEntry:    newEntry,
+		oldEntry:    oldEntry,
+		highlighter: newHighlighter(name),
+		viewport:    viewport.New(width, max(height-5, 1)),
+		loading:     true,
+		hunks:       newHunker(contextLines),
+	}
+	return m
+}
+
+func (m *Model) Init() tea.Cmd {
+	return tea.Batch(tea.ClearScreen, m.openCmd)
+}
+
+// openCmd opens both files through the snapshot source
+func (m *Model) openCmd() tea.Msg {
+	if m.metadata.Source == nil {
+		return openedMsg{err: fmt.Errorf("the snapshots can't be read")}
+	}
+	oldReader, err := m.metadata.Source.Open(m.metadata.Older, m.oldEntry.Path)
+	if err != nil {
+		return openedMsg{err: err}
+	}
+	newReader, err := m.metadata.Source.Open(m.metadata.Newer, m.newEntry.Path)
+	if err != nil {
+		_ = oldReader.Close()
+		return openedMsg{err: err}
+	}
+	return openedMsg{oldStream: newStream(oldReader), newStream: newStream(newReader)}
+}
+
+// loadCmd reads the next lines of both files
+func (m *Model) loadCmd() tea.Cmd {
+	m.loading = true
+	oldStream, newStream := m.oldStream, m.newStream
+	return func() tea.Msg {
+		oldLines, err := oldStream.readLines(chunkLines)
+		if err != nil {
+			return chunkMsg{err: err}
+		}
+		newLines, err := newStream.readLines(chunkLines)
+		return chunkMsg{oldLines: oldLines, newLines: newLines, err: err}
+	}
+}
+
+// close stops reading the files. restic dump reports its errors only
+// when the file is closed.
+func (m *Model) close() error {
+	var err error
+	if m.oldStream != nil {
+		err = m.oldStream.Close()
+	}
+	if m.newStream != nil {
+		err = errors.Join(err, m.newStream.Close())
+	}
+	m.oldStream, m.newStream = nil, nil
+	return err
+}
+
+func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.viewport.Width = msg.Width
+		m.viewport.Height = max(msg.Height-5, 1)
+		m.rerender()
+		return m, nil
+
+	case openedMsg:
+		if msg.err != nil {
+			m.loading = false
+			m.err = msg.err
+			return m, nil
+		}
+		m.oldStream, m.newStream = msg.oldStream, msg.newStream
+		var oldBinary, newBinary bool
+		m.mimeOld, oldBinary = m.oldStream.sniff()
+		m.mimeNew, newBinary = m.newStream.sniff()
+		if oldBinary || newBinary {
+			m.binary = true
+			m.loading = false
+			_ = m.close()
+			return m, nil
+		}
+		return m, m.loadCmd()
+
+	case chunkMsg:
+		m.loading = false
+		if msg.err != nil {
+			m.err = msg.err
+			_ = m.close()
+			return m, nil
+		}
+		m.addChunk(msg.oldLines, msg.newLines)
+		m.render()
+		if m.loadAll {
+			m.viewport.GotoBottom()
+		}
+		return m, m.maybeLoad()
+
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keyMap.Quit):
+			_ = m.close()
+			return m, tea.Quit
+
+		case key.Matches(msg, m.keyMap.Back):
+			_ = m.close()
+			return m.prevModel, func() tea.Msg {
+				return tea.WindowSizeMsg{Width: m.width, Height: m.height}
+			}
+
+		case key.Matches(msg, m.keyMap.Layout):
+			m.layout = (m.layout + 1) % 2
+			m.rerender()
+			return m, nil
+
+		case key.Matches(msg, m.keyMap.More):
+			m.loadAll = true
+			m.viewport.GotoBottom()
+			return m, m.maybeLoad()
+		}
+
+	case tea.MouseMsg:
+		// Scrolls the viewport
+
+	default:
+		return m, models.Forward(m.prevModel, msg)
+	}
+
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, tea.Batch(cmd, m.maybeLoad())
+}
+
+// maybeLoad reads more lines when the end of the diff is visible
+func (m *Model) maybeLoad() tea.Cmd {
+	if m.loading || m.done || m.binary || m.err != nil || m.oldStream == nil {
+		return nil
+	}
+	if m.loadAll || m.viewport.AtBottom() {
+		return m.loadCmd()
+	}
+	return nil
+}
+
+// addChunk diffs the new lines with the ones left from the previous
+// chunk. The lines after the last equal line are kept for the next
+// chunk, as they may match lines that weren't read yet.
+func (m *Model) addChunk(oldLines, newLines []string) {
+	oldLines = append(m.pendingOld, oldLines...)
+	newLines = append(m.pendingNew, newLines...)
+	lines := diffLines(oldLines, newLines, m.oldCount+1, m.newCount+1)
+	m.done = m.oldStream.done && m.newStream.done
+
+	keep := len(lines)
+	if !m.done {
+		for keep > 0 && lines[keep-1].Kind != Equal {
+			keep--
+		}
+		// Without equal lines, a change longer than a chunk goes on in
+		// the next one. Past maxPendingLines it's shown as it is.
+		if keep == 0 && (len(oldLines) >= maxPendingLines || len(newLines) >= maxPendingLines) {
+			keep = len(lines)
+		}
+	}
+
+	var oldUsed, newUsed int
+	for _, l := range lines[:keep] {
+		if l.Kind != Inserted {
+			oldUsed++
+		}
+		if l.Kind != Deleted {
+			newUsed++
+		}
+	}
+	m.hunks.add(lines[:keep])
+	m.pendingOld = oldLines[oldUsed:]
+	m.pendingNew = newLines[newUsed:]
+	m.oldCount += oldUsed
+	m.newCount += newUsed
+	if m.done {
+		m.err = m.close()
+	}
+}
+
+// render updates the content of the viewport. The closed hunks are
+// rendered once, the open one every time.
+func (m *Model) render() {
+	m.rendered.WriteString(m.renderHunks(m.hunks.closed[m.renderedHunks:]))
+	m.renderedHunks = len(m.hunks.closed)
+	content := m.rendered.String()
+	if h, ok := m.hunks.open(); ok {
+		content += m.renderHunks([]hunk{h})
+	}
+	m.viewport.SetContent(content)
+}
+
+// rerender renders all the hunks again, after a change of the layout
+// or the width
+func (m *Model) rerender() {
+	m.rendered.Reset()
+	m.renderedHunks = 0
+	m.render()
+}
+
+func (m *Model) renderHunks(hs []hunk) string {
+	if m.layout == SideBySide {
+		return renderSideBySide(hs, m.highlighter, m.width)
+	}
+	return renderUnified(hs, m.highlighter)
+}
+
+func (m *Model) View() string {
+	var output strings.Builder
+
+	header := fmt.Sprintf("Diff: %s  %s → %s  Layout: %s", m.name, m.metadata.OlderId, m.metadata.NewerId, m.layout)
+	output.WriteString(headerStyle.Render(header))
+	output.WriteString("\n")
+
+	largest := max(m.newEntry.Size, m.oldEntry.Size)
+	if largest >= LargeFileSize && !m.done {
+		output.WriteString(warningStyle.Render(fmt.Sprintf("Large file (%s): the diff is loaded as you scroll, G loads it all", humanize.Bytes(uint64(largest)))))
+	}
+	output.WriteString("\n")
+
+	switch {
+	case m.err != nil:
+		output.WriteString(fmt.Sprintf("Can't compare the files: %v", m.err))
+	case m.binary:
+		output.WriteString(fmt.Sprintf("Binary files: %s (%s) and %s (%s)\n",
+			m.mimeOld, m.oldEntry.SizeReadable, m.mimeNew, m.newEntry.SizeReadable))
+		output.WriteString("Press # in the compare view to compare their content.")
+	case m.done && !m.hunks.changed:
+		output.WriteString("The files have the same content.")
+	default:
+		output.WriteString(m.viewport.View())
+	}
+	output.WriteString("\n")
+
+	status := fmt.Sprintf("%d / %d lines compared", m.oldCount, m.newCount)
+	if m.loading {
+		status += ", loading..."
+	} else if m.done {
+		status += ", end of files"
+	}
+	output.WriteString(status)
+	output.WriteString("\n")
+	output.WriteString(m.help.View(m.keyMap))
+
+	return output.String()
+}
@@ -0,0 +1,175 @@
+package diffview
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Layout of the diff pane
+type Layout int
+
+const (
+	Unified Layout = iota
+	SideBySide
+)
+
+func (l Layout) String() string {
+	if l == SideBySide {
+		return "side by side"
+	}
+	return "unified"
+}
+
+// contextLines is the number of equal lines shown around the changes
+const contextLines = 3
+
+// commentPrefixes maps file extensions to their line comment prefix
+var commentPrefixes = map[string]string{
+	".go": "//", ".c": "//", ".h": "//", ".cpp": "//", ".java": "//", ".js": "//", ".ts": "//", ".rs": "//", ".css": "//",
+	".sh": "#", ".py": "#", ".rb": "#", ".pl": "#", ".yaml": "#", ".yml": "#", ".toml": "#", ".conf": "#", ".ini": "#", ".cfg": "#",
+	".sql": "--", ".lua": "--", ".hs": "--",
+	".el": ";", ".lisp": ";", ".clj": ";",
+	".tex": "%", ".m": "%",
+	".vim": "\"",
+}
+
+// highlighter colors the comments and string literals of a file type
+type highlighter struct {
+	comment string
+}
+
+func newHighlighter(name string) highlighter {
+	return highlighter{comment: commentPrefixes[strings.ToLower(filepath.Ext(name))]}
+}
+
+// render returns s with its comment and strings colored. Files of an
+// unknown type are returned as is.
+func (h highlighter) render(s string) string {
+	if h.comment == "" {
+		return s
+	}
+	var out strings.Builder
+	var quote rune
+	var literal strings.Builder
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			literal.WriteRune(r)
+			if r == quote {
+				out.WriteString(stringStyle.Render(literal.String()))
+				literal.Reset()
+				quote = 0
+			}
+		case strings.HasPrefix(s[i:], h.comment):
+			out.WriteString(commentStyle.Render(s[i:]))
+			return out.String()
+		case r == '"' || r == '\'' || r == '`':
+			quote = r
+			literal.WriteRune(r)
+		default:
+			out.WriteRune(r)
+		}
+	}
+	// An unterminated literal, e.g. a quote in the text
+	out.WriteString(literal.String())
+	return out.String()
+}
+
+// expandTabs replaces the tabs, which the viewport doesn't measure
+func expandTabs(s string) string {
+	return strings.ReplaceAll(s, "\t", "    ")
+}
+
+// cut returns s padded or truncated to width runes
+func cut(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) > width {
+		return string(runes[:max(width, 0)])
+	}
+	return s + strings.Repeat(" ", width-len(runes))
+}
+
+func lineNo(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return fmt.Sprint(n)
+}
+
+// renderUnified returns the hunks as a unified diff
+func renderUnified(hs []hunk, h highlighter) string {
+	var out strings.Builder
+	for _, hk := range hs {
+		out.WriteString(hunkStyle.Render(fmt.Sprintf("@@ -%d,%d +%d,%d @@", hk.oldStart, hk.oldCount, hk.newStart, hk.newCount)))
+		out.WriteString("\n")
+		for _, l := range hk.lines {
+			marker := " "
+			switch l.Kind {
+			case Deleted:
+				marker = deletedStyle.Render("-")
+			case Inserted:
+				marker = insertedStyle.Render("+")
+			}
+			numbers := lineNoStyle.Render(fmt.Sprintf("%5s %5s", lineNo(l.OldNo), lineNo(l.NewNo)))
+			fmt.Fprintf(&out, "%s %s %s\n", numbers, marker, h.render(expandTabs(l.Text)))
+		}
+	}
+	return out.String()
+}
+
+// renderSideBySide returns the hunks with the old file on the left and
+// the new one on the right. Deleted lines are paired with the inserted
+// lines that follow them.
+func renderSideBySide(hs []hunk, h highlighter, width int) string {
+	// Line number, marker and separator
+	column := max((width-3)/2-8, 10)
+	side := func(no int, kind Kind, text string) string {
+		marker := " "
+		switch kind {
+		case Deleted:
+			marker = deletedStyle.Render("-")
+		case Inserted:
+			marker = insertedStyle.Render("+")
+		}
+		if no == 0 {
+			return strings.Repeat(" ", column+8)
+		}
+		return fmt.Sprintf("%s %s %s", lineNoStyle.Render(fmt.Sprintf("%5d", no)), marker, h.render(cut(expandTabs(text), column)))
+	}
+
+	var out strings.Builder
+	for _, hk := range hs {
+		out.WriteString(hunkStyle.Render(fmt.Sprintf("@@ -%d,%d +%d,%d @@", hk.oldStart, hk.oldCount, hk.newStart, hk.newCount)))
+		out.WriteString("\n")
+		lines := hk.lines
+		for i := 0; i < len(lines); {
+			if lines[i].Kind == Equal {
+				l := lines[i]
+				fmt.Fprintf(&out, "%s │ %s\n", side(l.OldNo, Equal, l.Text), side(l.NewNo, Equal, l.Text))
+				i++
+				continue
+			}
+			var deleted, inserted []Line
+			for i < len(lines) && lines[i].Kind == Deleted {
+				deleted = append(deleted, lines[i])
+				i++
+			}
+			for i < len(lines) && lines[i].Kind == Inserted {
+				inserted = append(inserted, lines[i])
+				i++
+			}
+			for k := 0; k < max(len(deleted), len(inserted)); k++ {
+				var left, right Line
+				if k < len(deleted) {
+					left = deleted[k]
+				}
+				if k < len(inserted) {
+					right = inserted[k]
+				}
+				fmt.Fprintf(&out, "%s │ %s\n", side(left.OldNo, Deleted, left.Text), side(right.NewNo, Inserted, right.Text))
+			}
+		}
+	}
+	return out.String()
+}
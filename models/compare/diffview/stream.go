@@ -0,0 +1,52 @@
+package diffview
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// sniffLen is the number of bytes used to detect binary files
+const sniffLen = 8000
+
+// stream reads the lines of a file in chunks
+type stream struct {
+	reader *bufio.Reader
+	closer io.Closer
+	done   bool
+}
+
+func newStream(r io.ReadCloser) *stream {
+	return &stream{reader: bufio.NewReaderSize(r, 64*1024), closer: r}
+}
+
+// sniff returns the MIME type of the file and if it's binary. Like
+// diff, files with a NUL byte in the first bytes are binary.
+func (s *stream) sniff() (string, bool) {
+	head, _ := s.reader.Peek(sniffLen)
+	return http.DetectContentType(head), bytes.IndexByte(head, 0) >= 0
+}
+
+// readLines returns up to n lines, without the line endings
+func (s *stream) readLines(n int) ([]string, error) {
+	var lines []string
+	for !s.done && len(lines) < n {
+		line, err := s.reader.ReadString('\n')
+		if err == io.EOF {
+			s.done = true
+			if line == "" {
+				break
+			}
+		} else if err != nil {
+			return lines, err
+		}
+		lines = append(lines, strings.TrimRight(line, "\r\n"))
+	}
+	return lines, nil
+}
+
+func (s *stream) Close() error {
+	return s.closer.Close()
+}
@@ -0,0 +1,31 @@
+package diffview
+
+import "github.com/charmbracelet/lipgloss"
+
+var headerStyle = lipgloss.NewStyle().
+	Bold(true).
+	Padding(0, 1)
+
+var warningStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#fdbc4b"))
+
+var hunkStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#3daee9"))
+
+var deletedStyle = lipgloss.NewStyle().
+	Bold(true).
+	Foreground(lipgloss.Color("#ed1515"))
+
+var insertedStyle = lipgloss.NewStyle().
+	Bold(true).
+	Foreground(lipgloss.Color("#1cdc9a"))
+
+var lineNoStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#7f8c8d"))
+
+var commentStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#7f8c8d")).
+	Italic(true)
+
+var stringStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#f67400"))
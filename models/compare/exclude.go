@@ -0,0 +1,62 @@
+package compare
+
+import (
+	"path/filepath"
+
+	"gestic/models/compare/exclude"
+)
+
+// toggleMark marks or unmarks n to be excluded from the next backups
+func (m *Model) toggleMark(n *Node) {
+	if m.session.marked[n] {
+		delete(m.session.marked, n)
+	} else {
+		m.session.marked[n] = true
+	}
+}
+
+// excludeRules returns a pattern for each marked node of the tree of
+// root, in tree order. Nodes inside a marked directory are left out.
+func excludeRules(root *Node, marked map[*Node]bool) []exclude.Rule {
+	var rules []exclude.Rule
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		if marked[n] {
+			if rule, ok := excludeRule(root, n); ok {
+				rules = append(rules, rule)
+			}
+			return
+		}
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	walk(root)
+	return rules
+}
+
+// excludeRule returns the pattern matching n in the backups of root,
+// and the size it has in the newer snapshot. A removed entry is only
+// in the older snapshot, its path is relative to the older root.
+func excludeRule(root, n *Node) (exclude.Rule, bool) {
+	rootEntry, entry := root.New, n.New
+	if entry == nil {
+		rootEntry, entry = root.Old, n.Old
+	}
+	if rootEntry == nil {
+		return exclude.Rule{}, false
+	}
+	rel, err := filepath.Rel(rootEntry.Path, entry.Path)
+	if err != nil {
+		return exclude.Rule{}, false
+	}
+
+	var size int64
+	if n.New != nil {
+		size = n.New.Size
+	}
+	return exclude.Rule{
+		Pattern: "/" + exclude.EscapePattern(filepath.ToSlash(rel)),
+		Bytes:   size,
+	}, true
+}
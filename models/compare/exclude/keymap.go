@@ -0,0 +1,49 @@
+package exclude
+
+import "github.com/charmbracelet/bubbles/key"
+
+type keymap struct {
+	Write  key.Binding
+	File   key.Binding
+	Append key.Binding
+	Back   key.Binding
+	Quit   key.Binding
+}
+
+func (k keymap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Write, k.File, k.Append, k.Back}
+}
+
+func (k keymap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Write, k.File},
+		{k.Append, k.Back},
+		{k.Quit},
+	}
+}
+
+// DefaultKeyMap returns the keys of the exclude screen
+func DefaultKeyMap() keymap {
+	return keymap{
+		Write: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "Write"),
+		),
+		File: key.NewBinding(
+			key.WithKeys("f"),
+			key.WithHelp("f", "Edit file"),
+		),
+		Append: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", "Append/replace"),
+		),
+		Back: key.NewBinding(
+			key.WithKeys("esc", "q"),
+			key.WithHelp("esc/q", "Back"),
+		),
+		Quit: key.NewBinding(
+			key.WithKeys("ctrl+c"),
+			key.WithHelp("ctrl+c", "quit"),
+		),
+	}
+}
@@ -0,0 +1,155 @@
+package exclude
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gestic/models"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dustin/go-humanize"
+)
+
+// DefaultFile is the exclude file written without --exclude-file
+const DefaultFile = "excludes.txt"
+
+// Model previews the exclude rules of the marked rows and writes them
+// to an exclude file
+type Model struct {
+	prevModel tea.Model
+	help      help.Model
+	keyMap    keymap
+	width     int
+	height    int
+
+	rules       []Rule
+	fileInput   textinput.Model
+	appendRules bool // Append to the file if it exists
+	status      string
+}
+
+// InitialModel returns the exclude screen of rules. They are appended
+// to file if it exists.
+func InitialModel(prevModel tea.Model, width, height int, rules []Rule, file string) *Model {
+	if file == "" {
+		file = DefaultFile
+	}
+	fileInput := textinput.New()
+	fileInput.Prompt = ""
+	fileInput.SetValue(file)
+
+	return &Model{
+		prevModel:   prevModel,
+		help:        help.New(),
+		keyMap:      DefaultKeyMap(),
+		width:       width,
+		height:      height,
+		rules:       rules,
+		fileInput:   fileInput,
+		appendRules: true,
+	}
+}
+
+func (m *Model) Init() tea.Cmd {
+	return tea.ClearScreen
+}
+
+func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		return m, nil
+
+	case tea.KeyMsg:
+		if key.Matches(msg, m.keyMap.Quit) {
+			return m, tea.Quit
+		}
+		if m.fileInput.Focused() {
+			switch msg.Type {
+			case tea.KeyEnter, tea.KeyEsc:
+				m.fileInput.Blur()
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.fileInput, cmd = m.fileInput.Update(msg)
+			return m, cmd
+		}
+
+		switch {
+		case key.Matches(msg, m.keyMap.Back):
+			return m.prevModel, func() tea.Msg {
+				return tea.WindowSizeMsg{Width: m.width, Height: m.height}
+			}
+
+		case key.Matches(msg, m.keyMap.Write):
+			if len(m.rules) == 0 {
+				return m, nil
+			}
+			appendRules := m.appendRules && m.fileExists()
+			if err := WriteRules(m.fileInput.Value(), m.rules, appendRules); err != nil {
+				m.status = err.Error()
+			} else if appendRules {
+				m.status = fmt.Sprintf("Appended %d patterns to %s", len(m.rules), m.fileInput.Value())
+			} else {
+				m.status = fmt.Sprintf("Wrote %d patterns to %s", len(m.rules), m.fileInput.Value())
+			}
+			return m, nil
+
+		case key.Matches(msg, m.keyMap.File):
+			m.fileInput.CursorEnd()
+			return m, m.fileInput.Focus()
+
+		case key.Matches(msg, m.keyMap.Append):
+			m.appendRules = !m.appendRules
+			return m, nil
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.fileInput, cmd = m.fileInput.Update(msg)
+	return m, tea.Batch(cmd, models.Forward(m.prevModel, msg))
+}
+
+func (m *Model) fileExists() bool {
+	_, err := os.Stat(m.fileInput.Value())
+	return err == nil
+}
+
+func (m *Model) View() string {
+	var output strings.Builder
+
+	output.WriteString(headerStyle.Render("Exclude rules"))
+	output.WriteString("\n\n")
+
+	if len(m.rules) == 0 {
+		output.WriteString("No rows are marked. Mark them with x in the compare view.\n")
+	}
+	var total int64
+	for _, r := range m.rules {
+		fmt.Fprintf(&output, "%10s  %s\n", humanize.Bytes(uint64(r.Bytes)), r.Pattern)
+		total += r.Bytes
+	}
+	output.WriteString("\n")
+	fmt.Fprintf(&output, "These patterns would have saved %s in the newer snapshot.\n\n", humanize.Bytes(uint64(total)))
+
+	fmt.Fprintf(&output, "%s %s\n", labelStyle.Render("File:"), m.fileInput.View())
+	switch {
+	case !m.fileExists():
+		output.WriteString("The file will be created.\n")
+	case m.appendRules:
+		output.WriteString("The file exists, the patterns will be appended to it.\n")
+	default:
+		output.WriteString("The file exists and will be replaced.\n")
+	}
+	output.WriteString(m.status)
+	output.WriteString("\n\n")
+	output.WriteString(m.help.View(m.keyMap))
+	return output.String()
+}
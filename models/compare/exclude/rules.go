@@ -0,0 +1,62 @@
+package exclude
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Rule is a restic exclude pattern and the bytes it leaves out of the
+// newer snapshot
+type Rule struct {
+	Pattern string
+	Bytes   int64
+}
+
+// EscapePattern escapes the characters restic patterns give a meaning to
+func EscapePattern(path string) string {
+	var escaped strings.Builder
+	for _, r := range path {
+		switch r {
+		case '*', '?', '[', '\\':
+			escaped.WriteRune('\\')
+		}
+		escaped.WriteRune(r)
+	}
+	return escaped.String()
+}
+
+// WriteRules writes the patterns to an exclude file, after the existing
+// lines if appending
+func WriteRules(path string, rules []Rule, appendRules bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appendRules {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+
+	var prefix string
+	if appendRules {
+		// Don't join the first pattern with an unterminated last line
+		if content, err := os.ReadFile(path); err == nil && len(content) > 0 && content[len(content)-1] != '\n' {
+			prefix = "\n"
+		}
+	}
+
+	f, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return fmt.Errorf("can't open exclude file: %w", err)
+	}
+	defer f.Close()
+
+	var content strings.Builder
+	content.WriteString(prefix)
+	fmt.Fprintf(&content, "# Added by gestic on %s\n", time.Now().Format("2006-01-02 15:04:05"))
+	for _, r := range rules {
+		content.WriteString(r.Pattern + "\n")
+	}
+	if _, err := f.WriteString(content.String()); err != nil {
+		return fmt.Errorf("can't write exclude file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package exclude
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEscapePattern(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"docs/report.txt", "docs/report.txt"},
+		{"a*b?c", `a\*b\?c`},
+		{"[draft]", `\[draft]`},
+		{`back\slash`, `back\\slash`},
+		{"café", "café"},
+	}
+	for _, tt := range tests {
+		if got := EscapePattern(tt.path); got != tt.want {
+			t.Errorf("EscapePattern(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestWriteRules(t *testing.T) {
+	rules := []Rule{{Pattern: "/a", Bytes: 1}, {Pattern: "/b", Bytes: 2}}
+	tests := []struct {
+		name     string
+		existing string // Content before the write, none if empty
+		append   bool
+		want     []string // Lines after the write, the comment left out
+	}{
+		{"new file", "", true, []string{"/a", "/b"}},
+		{"append", "/old\n", true, []string{"/old", "/a", "/b"}},
+		{"unterminated line", "/old", true, []string{"/old", "/a", "/b"}},
+		{"replace", "/old\n", false, []string{"/a", "/b"}},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "excludes")
+		if tt.existing != "" {
+			if err := os.WriteFile(path, []byte(tt.existing), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := WriteRules(path, rules, tt.append); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var lines []string
+		for _, line := range strings.Split(strings.TrimSuffix(string(content), "\n"), "\n") {
+			if !strings.HasPrefix(line, "# Added by gestic") {
+				lines = append(lines, line)
+			}
+		}
+		if strings.Join(lines, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s: lines = %q, want %q", tt.name, lines, tt.want)
+		}
+	}
+}
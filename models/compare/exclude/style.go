@@ -0,0 +1,10 @@
+package exclude
+
+import "github.com/charmbracelet/lipgloss"
+
+var headerStyle = lipgloss.NewStyle().
+	Bold(true).
+	Padding(0, 1)
+
+var labelStyle = lipgloss.NewStyle().
+	Bold(true)
@@ -0,0 +1,45 @@
+package compare
+
+import (
+	"slices"
+	"testing"
+
+	"gestic/models/compare/exclude"
+)
+
+func TestExcludeRules(t *testing.T) {
+	newer, older := loadTrees(t, map[string]string{
+		"cache/a.bin":     "aaaa",
+		"cache/b.bin":     "bb",
+		"docs/[draft].md": "draft",
+		"docs/keep.md":    "k",
+	}, map[string]string{
+		"docs/keep.md": "k",
+		"old.log":      "log",
+	})
+	tree := BuildTree(newer, older)
+	cache := child(t, tree, "cache")
+	docs := child(t, tree, "docs")
+
+	tests := []struct {
+		name   string
+		marked []*Node
+		want   []exclude.Rule
+	}{
+		{"none", nil, nil},
+		{"directory", []*Node{cache}, []exclude.Rule{{Pattern: "/cache", Bytes: 6}}},
+		{"inside a marked directory", []*Node{child(t, cache, "a.bin"), cache}, []exclude.Rule{{Pattern: "/cache", Bytes: 6}}},
+		{"escaped", []*Node{child(t, docs, "[draft].md")}, []exclude.Rule{{Pattern: `/docs/\[draft].md`, Bytes: 5}}},
+		{"removed", []*Node{child(t, tree, "old.log")}, []exclude.Rule{{Pattern: "/old.log"}}},
+		{"tree order", []*Node{child(t, tree, "old.log"), child(t, cache, "b.bin")}, []exclude.Rule{{Pattern: "/cache/b.bin", Bytes: 2}, {Pattern: "/old.log"}}},
+	}
+	for _, tt := range tests {
+		marked := make(map[*Node]bool)
+		for _, n := range tt.marked {
+			marked[n] = true
+		}
+		if got := excludeRules(tree, marked); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: rules = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package compare
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Export formats
+const (
+	FormatJSON     = "json"      // Flat list of records
+	FormatJSONTree = "json-tree" // Records nested by directory
+	FormatCSV      = "csv"
+	FormatNDJSON   = "ndjson" // One record per line
+)
+
+// Record is an exported row of the comparison
+type Record struct {
+	Path      string    `json:"path"`
+	NewSize   int64     `json:"new_size"`
+	OldSize   int64     `json:"old_size"`
+	Diff      int64     `json:"diff"`
+	NewFiles  int64     `json:"new_files"`
+	OldFiles  int64     `json:"old_files"`
+	FilesDiff int64     `json:"files_diff"`
+	Status    string    `json:"status"`
+	MovedFrom string    `json:"moved_from,omitempty"`
+	Children  []*Record `json:"children,omitempty"`
+}
+
+// CreateRecords returns the children of node as records, nested up
+// to depth. A depth of 0 means no limit.
+func CreateRecords(node *Node, depth int) []*Record {
+	var records []*Record
+	for _, n := range node.Children {
+		record := &Record{
+			Path:      "/" + n.RelPath,
+			Diff:      n.Diff,
+			FilesDiff: n.FilesDiff,
+			Status:    n.Status,
+		}
+		if n.Status == StatusMoved {
+			record.MovedFrom = "/" + n.MovedFrom
+		}
+		if n.New != nil {
+			record.NewSize = n.New.Size
+			record.NewFiles = n.New.Files
+		}
+		if n.Old != nil {
+			record.OldSize = n.Old.Size
+			record.OldFiles = n.Old.Files
+		}
+		records = append(records, record)
+
+		if depth != 1 {
+			record.Children = CreateRecords(n, max(depth-1, 0))
+		}
+	}
+	return records
+}
+
+// FlattenRecords returns the records and their children in depth-first
+// order. The returned records have no children.
+func FlattenRecords(records []*Record) []*Record {
+	var flat []*Record
+	for _, r := range records {
+		c := *r
+		c.Children = nil
+		flat = append(flat, &c)
+		flat = append(flat, FlattenRecords(r.Children)...)
+	}
+	return flat
+}
+
+// Export writes the records to w in the given format. The flat formats
+// include the children of each record. It returns the number of records.
+func Export(w io.Writer, format string, records []*Record) (int, error) {
+	flat := FlattenRecords(records)
+
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if flat == nil {
+			flat = []*Record{}
+		}
+		return len(flat), encoder.Encode(flat)
+
+	case FormatJSONTree:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if records == nil {
+			records = []*Record{}
+		}
+		return len(flat), encoder.Encode(records)
+
+	case FormatNDJSON:
+		encoder := json.NewEncoder(w)
+		for _, r := range flat {
+			if err := encoder.Encode(r); err != nil {
+				return 0, err
+			}
+		}
+		return len(flat), nil
+
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		_ = writer.Write([]string{"path", "new_size", "old_size", "diff", "new_files", "old_files", "files_diff", "status", "moved_from"})
+		for _, r := range flat {
+			_ = writer.Write([]string{
+				r.Path,
+				strconv.FormatInt(r.NewSize, 10),
+				strconv.FormatInt(r.OldSize, 10),
+				strconv.FormatInt(r.Diff, 10),
+				strconv.FormatInt(r.NewFiles, 10),
+				strconv.FormatInt(r.OldFiles, 10),
+				strconv.FormatInt(r.FilesDiff, 10),
+				r.Status,
+				r.MovedFrom,
+			})
+		}
+		writer.Flush()
+		return len(flat), writer.Error()
+	}
+
+	return 0, fmt.Errorf("unknown export format %q", format)
+}
+
+// ExportExtension returns the file extension for format
+func ExportExtension(format string) string {
+	switch format {
+	case FormatCSV:
+		return "csv"
+	case FormatNDJSON:
+		return "ndjson"
+	default:
+		return "json"
+	}
+}
@@ -0,0 +1,49 @@
+package compare
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	records := []*Record{
+		{Path: "/dir", NewSize: 30, OldSize: 10, Diff: 20, NewFiles: 2, OldFiles: 1, FilesDiff: 1, Status: StatusChanged, Children: []*Record{
+			{Path: "/dir/a,b", NewSize: 20, Diff: 20, NewFiles: 1, FilesDiff: 1, Status: StatusAdded},
+		}},
+		{Path: "/new", NewSize: 5, OldSize: 5, NewFiles: 1, OldFiles: 1, Status: StatusMoved, MovedFrom: "/old"},
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FormatCSV, `path,new_size,old_size,diff,new_files,old_files,files_diff,status,moved_from
+/dir,30,10,20,2,1,1,changed,
+"/dir/a,b",20,0,20,1,0,1,added,
+/new,5,5,0,1,1,0,moved,/old
+`},
+		{FormatNDJSON, `{"path":"/dir","new_size":30,"old_size":10,"diff":20,"new_files":2,"old_files":1,"files_diff":1,"status":"changed"}
+{"path":"/dir/a,b","new_size":20,"old_size":0,"diff":20,"new_files":1,"old_files":0,"files_diff":1,"status":"added"}
+{"path":"/new","new_size":5,"old_size":5,"diff":0,"new_files":1,"old_files":1,"files_diff":0,"status":"moved","moved_from":"/old"}
+`},
+	}
+	for _, tt := range tests {
+		var output strings.Builder
+		count, err := Export(&output, tt.format, records)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if count != 3 || output.String() != tt.want {
+			t.Errorf("%s: %d records:\n%s\nwant 3:\n%s", tt.format, count, output.String(), tt.want)
+		}
+	}
+
+	for _, format := range []string{FormatJSON, FormatJSONTree} {
+		var output strings.Builder
+		if _, err := Export(&output, format, nil); err != nil || output.String() != "[]\n" {
+			t.Errorf("%s of no records = %q, %v, want an empty list", format, output.String(), err)
+		}
+	}
+	if _, err := Export(&strings.Builder{}, "xml", records); err == nil {
+		t.Errorf("xml: no error")
+	}
+}
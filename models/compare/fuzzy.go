@@ -0,0 +1,204 @@
+package compare
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// fuzzyMatch checks if the runes of pattern appear in s in the same
+// order, ignoring case. It returns the rune indexes of s that matched
+// and a score, where lower is better: consecutive runes and matches
+// close to the start are preferred.
+func fuzzyMatch(pattern, s string) ([]int, int, bool) {
+	p := []rune(strings.ToLower(pattern))
+	if len(p) == 0 {
+		return nil, 0, true
+	}
+
+	var matched []int
+	score := 0
+	last := -1
+	for i, r := range []rune(s) {
+		if unicode.ToLower(r) != p[len(matched)] {
+			continue
+		}
+		if last == -1 {
+			score += i
+		} else {
+			score += (i - last - 1) * 2
+		}
+		matched = append(matched, i)
+		last = i
+		if len(matched) == len(p) {
+			return matched, score, true
+		}
+	}
+	return nil, 0, false
+}
+
+// highlight renders the runes of s at the matched indexes with style
+func highlight(s string, matched []int, render func(...string) string) string {
+	if len(matched) == 0 {
+		return s
+	}
+	isMatched := make(map[int]bool, len(matched))
+	for _, i := range matched {
+		isMatched[i] = true
+	}
+
+	var b strings.Builder
+	for i, r := range []rune(s) {
+		if isMatched[i] {
+			b.WriteString(render(string(r)))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// highlightRow underlines the runes of the names of a row rendered by
+// the table that match pattern. The cells are truncated by then, so a
+// name ending with "…" can show only the start of the match. The row
+// keeps the selected style.
+func highlightRow(line string, columns []table.Column, pattern string) string {
+	plain := ansi.Strip(line)
+	base := lipgloss.NewStyle()
+	if plain != line {
+		base = tableStyles.Selected
+	}
+	match := matchStyle.Inherit(base)
+
+	// Each cell has a padding of 1. The hidden entries summary and the
+	// empty lines have no diff.
+	var starts []int
+	start := 0
+	for _, c := range columns {
+		starts = append(starts, start+1)
+		start += c.Width + 2
+	}
+	if len(columns) < 3 || strings.TrimSpace(ansi.Cut(plain, starts[2], starts[2]+columns[2].Width)) == "" {
+		return line
+	}
+
+	var b strings.Builder
+	pos := 0
+	for i := 0; i < 2; i++ {
+		// The name comes after the size
+		nameStart, nameEnd := starts[i]+MaxColSize+1, starts[i]+columns[i].Width
+		b.WriteString(base.Render(ansi.Cut(plain, pos, nameStart)))
+
+		name := strings.TrimRight(ansi.Cut(plain, nameStart, nameEnd), " ")
+		matched, _, ok := fuzzyMatch(pattern, name)
+		if !ok && strings.HasSuffix(name, "…") {
+			matched = matchedPrefix(pattern, name)
+		}
+		b.WriteString(highlightStyled(name, matched, base, match))
+		pos = nameStart + ansi.StringWidth(name)
+	}
+	b.WriteString(base.Render(ansi.Cut(plain, pos, ansi.StringWidth(plain))))
+	return b.String()
+}
+
+// matchedPrefix returns the rune indexes of s matching the start of
+// pattern, like fuzzyMatch does
+func matchedPrefix(pattern, s string) []int {
+	p := []rune(strings.ToLower(pattern))
+	var matched []int
+	for i, r := range []rune(s) {
+		if len(matched) == len(p) {
+			break
+		}
+		if unicode.ToLower(r) == p[len(matched)] {
+			matched = append(matched, i)
+		}
+	}
+	return matched
+}
+
+// highlightStyled renders s with base, and the runes at the matched
+// indexes with match
+func highlightStyled(s string, matched []int, base, match lipgloss.Style) string {
+	isMatched := make(map[int]bool, len(matched))
+	for _, i := range matched {
+		isMatched[i] = true
+	}
+
+	var b strings.Builder
+	var run []rune
+	runMatched := false
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		if runMatched {
+			b.WriteString(match.Render(string(run)))
+		} else {
+			b.WriteString(base.Render(string(run)))
+		}
+		run = run[:0]
+	}
+	for i, r := range []rune(s) {
+		if isMatched[i] != runMatched {
+			flush()
+			runMatched = isMatched[i]
+		}
+		run = append(run, r)
+	}
+	flush()
+	return b.String()
+}
+
+// filterNodes returns the nodes whose readable path matches pattern,
+// keeping their order
+func filterNodes(nodes []*Node, pattern string) []*Node {
+	if pattern == "" {
+		return nodes
+	}
+	var filtered []*Node
+	for _, n := range nodes {
+		if _, _, ok := fuzzyMatch(pattern, n.PathReadable()); ok {
+			filtered = append(filtered, n)
+		}
+	}
+	return filtered
+}
+
+// searchTree returns up to limit nodes under root whose relative path
+// matches pattern, best matches first
+func searchTree(root *Node, pattern string, limit int) []*Node {
+	type result struct {
+		node  *Node
+		score int
+	}
+	var results []result
+
+	var walk func(*Node)
+	walk = func(n *Node) {
+		for _, c := range n.Children {
+			if _, score, ok := fuzzyMatch(pattern, c.RelPath); ok {
+				results = append(results, result{node: c, score: score})
+			}
+			walk(c)
+		}
+	}
+	walk(root)
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score < results[j].score
+		}
+		return len(results[i].node.RelPath) < len(results[j].node.RelPath)
+	})
+
+	var nodes []*Node
+	for i := 0; i < len(results) && i < limit; i++ {
+		nodes = append(nodes, results[i].node)
+	}
+	return nodes
+}
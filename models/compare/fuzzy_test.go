@@ -0,0 +1,99 @@
+package compare
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		matched    []int
+		score      int
+		ok         bool
+	}{
+		{"", "anything", nil, 0, true},
+		{"abc", "abc", []int{0, 1, 2}, 0, true},
+		{"ABC", "xaBc", []int{1, 2, 3}, 1, true},
+		{"ac", "abc", []int{0, 2}, 2, true},
+		{"dł", "dół", []int{0, 2}, 2, true},
+		{"cb", "abc", nil, 0, false},
+		{"abcd", "abc", nil, 0, false},
+	}
+	for _, tt := range tests {
+		matched, score, ok := fuzzyMatch(tt.pattern, tt.s)
+		if fmt.Sprint(matched) != fmt.Sprint(tt.matched) || score != tt.score || ok != tt.ok {
+			t.Errorf("fuzzyMatch(%q, %q) = %v, %d, %v, want %v, %d, %v", tt.pattern, tt.s, matched, score, ok, tt.matched, tt.score, tt.ok)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	brackets := func(s ...string) string { return "[" + strings.Join(s, "") + "]" }
+	tests := []struct {
+		s       string
+		matched []int
+		want    string
+	}{
+		{"abc", nil, "abc"},
+		{"abc", []int{0, 2}, "[a]b[c]"},
+		{"dół", []int{1}, "d[ó]ł"},
+	}
+	for _, tt := range tests {
+		if got := highlight(tt.s, tt.matched, brackets); got != tt.want {
+			t.Errorf("highlight(%q, %v) = %q, want %q", tt.s, tt.matched, got, tt.want)
+		}
+	}
+}
+
+func TestMatchedPrefix(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		matched    []int
+	}{
+		{"report", "re…", []int{0, 1}},
+		{"rpt", "a rep…", []int{2, 4}},
+		{"xyz", "abc…", nil},
+	}
+	for _, tt := range tests {
+		if matched := matchedPrefix(tt.pattern, tt.s); fmt.Sprint(matched) != fmt.Sprint(tt.matched) {
+			t.Errorf("matchedPrefix(%q, %q) = %v, want %v", tt.pattern, tt.s, matched, tt.matched)
+		}
+	}
+}
+
+func TestSearchTree(t *testing.T) {
+	newer, older := loadTrees(t, map[string]string{
+		"docs/report.txt":     "r",
+		"docs/old/report.txt": "r",
+		"src/repo.go":         "r",
+		"readme":              "r",
+	}, map[string]string{"readme": "r"})
+	tree := BuildTree(newer, older)
+
+	tests := []struct {
+		pattern string
+		limit   int
+		want    []string
+	}{
+		{"report", 10, []string{"docs/report.txt", "docs/old/report.txt"}},
+		{"repo", 10, []string{"docs/report.txt", "src/repo.go", "docs/old/report.txt"}},
+		{"repo", 1, []string{"docs/report.txt"}},
+		{"zzz", 10, nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, n := range searchTree(tree, tt.pattern, tt.limit) {
+			got = append(got, n.RelPath)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("searchTree(%q, %d) = %v, want %v", tt.pattern, tt.limit, got, tt.want)
+		}
+	}
+
+	docs := child(t, tree, "docs")
+	if filtered := filterNodes(docs.Children, "old"); len(filtered) != 1 || filtered[0].Name != "old" {
+		t.Errorf("filterNodes(old) = %v, want the old directory", filtered)
+	}
+}
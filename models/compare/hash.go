@@ -0,0 +1,105 @@
+package compare
+
+import (
+	"fmt"
+
+	"gestic/restic"
+
+	"github.com/charmbracelet/bubbletea"
+)
+
+// HashDoneMsg is sent when the files under a node were hashed
+type HashDoneMsg struct {
+	Node   *Node
+	Hashes map[*restic.DirData]string
+	Err    error
+}
+
+func (HashDoneMsg) Background() {}
+
+// hashCandidates returns the entries under n found in both snapshots
+// with the same size, the only ones hashing can tell apart
+func hashCandidates(n *Node) []*Node {
+	if n.New == nil || n.Old == nil {
+		return nil
+	}
+	if !n.New.IsDir {
+		if n.New.Size != n.Old.Size || (n.New.Hash != "" && n.Old.Hash != "") {
+			return nil
+		}
+		return []*Node{n}
+	}
+	var files []*Node
+	for _, c := range n.Children {
+		files = append(files, hashCandidates(c)...)
+	}
+	return files
+}
+
+// hashCmd hashes the content of the files under n in both snapshots.
+// The hashes are set in Update, so the tree isn't changed concurrently.
+func (m *Model) hashCmd(n *Node) tea.Cmd {
+	files := hashCandidates(n)
+	metadata := m.metadata
+	return func() tea.Msg {
+		hashes := make(map[*restic.DirData]string)
+		if metadata.Source == nil {
+			return HashDoneMsg{Node: n, Hashes: hashes, Err: fmt.Errorf("the snapshots can't be read")}
+		}
+		for _, f := range files {
+			for _, entry := range []struct {
+				snapshot restic.Snapshot
+				data     *restic.DirData
+			}{{metadata.Newer, f.New}, {metadata.Older, f.Old}} {
+				hash, err := restic.HashEntry(metadata.Source, entry.snapshot, entry.data)
+				if err != nil {
+					return HashDoneMsg{Node: n, Hashes: hashes, Err: err}
+				}
+				hashes[entry.data] = hash
+			}
+		}
+		return HashDoneMsg{Node: n, Hashes: hashes}
+	}
+}
+
+// applyHashes sets the hashes of the entries under n and updates the
+// status of n, its subtree and its ancestors
+func applyHashes(n *Node, hashes map[*restic.DirData]string) {
+	for d, hash := range hashes {
+		d.Hash = hash
+	}
+	n.recount()
+	for p := n.Parent; p != nil; p = p.Parent {
+		p.recountLevel()
+	}
+}
+
+// contentChanged reports if n is a file found in both snapshots with a
+// different content, or a directory containing one
+func contentChanged(n *Node) bool {
+	if n.Status != StatusChanged && n.Status != StatusModified {
+		return false
+	}
+	if n.New.IsDir {
+		return n.Stats.Changed+n.Stats.Modified > 0
+	}
+	return true
+}
+
+// countModified returns the number of files under n with a different content
+func countModified(n *Node) int {
+	if n.New == nil || n.Old == nil {
+		return 0
+	}
+	if !n.New.IsDir {
+		if n.Status == StatusModified {
+			return 1
+		}
+		return 0
+	}
+	count := 0
+	for _, c := range n.Children {
+		count += countModified(c)
+	}
+	return count
+}
@@ -3,22 +3,53 @@ package compare
 import "github.com/charmbracelet/bubbles/key"
 
 type keymap struct {
-	NextDir   key.Binding
-	PrevDir   key.Binding
-	Clipboard key.Binding
-	Quit      key.Binding
-	Help      key.Binding
+	NextDir    key.Binding
+	PrevDir    key.Binding
+	Root       key.Binding
+	Breadcrumb key.Binding
+	Clipboard  key.Binding
+	Sort       key.Binding
+	Filter     key.Binding
+	Search     key.Binding
+
+	HideUnchanged key.Binding
+	OnlyModified  key.Binding
+	Threshold     key.Binding
+	Hash          key.Binding
+	Diff          key.Binding
+	Preview       key.Binding
+	Restore       key.Binding
+	Mark          key.Binding
+	Exclude       key.Binding
+	Export        key.Binding
+	Quit          key.Binding
+	Help          key.Binding
+
+	// Used by the filter and search inputs
+	Confirm    key.Binding
+	Cancel     key.Binding
+	ResultUp   key.Binding
+	ResultDown key.Binding
+	CrumbLeft  key.Binding
+	CrumbRight key.Binding
 }
 
 func (k keymap) ShortHelp() []key.Binding {
-	return []key.Binding{k.NextDir, k.PrevDir, k.Clipboard}
+	return []key.Binding{k.NextDir, k.PrevDir, k.Clipboard, k.Sort}
 }
 
 func (k keymap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.NextDir, k.Help}, // first column
 		{k.PrevDir, k.Quit}, // second column
-		{k.Clipboard},
+		{k.Clipboard, k.Sort},
+		{k.Root, k.Breadcrumb},
+		{k.Filter, k.Search},
+		{k.HideUnchanged, k.Threshold},
+		{k.OnlyModified, k.Hash},
+		{k.Diff, k.Preview},
+		{k.Restore, k.Export},
+		{k.Mark, k.Exclude},
 	}
 }
 
@@ -41,9 +72,93 @@ func DefaultKeyMap() keymap {
 			key.WithKeys("h", "left", "backspace"),
 			key.WithHelp("h/left", "Back"),
 		),
+		Root: key.NewBinding(
+			key.WithKeys("~"),
+			key.WithHelp("~", "Go to root"),
+		),
+		Breadcrumb: key.NewBinding(
+			key.WithKeys("B"),
+			key.WithHelp("B", "Go to parent..."),
+		),
 		Clipboard: key.NewBinding(
 			key.WithKeys("1", "2", "3"),
 			key.WithHelp("1,2,3", "Copy"),
 		),
+		Sort: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "Sort"),
+		),
+		Filter: key.NewBinding(
+			key.WithKeys("/"),
+			key.WithHelp("/", "Filter"),
+		),
+		Search: key.NewBinding(
+			key.WithKeys("ctrl+f"),
+			key.WithHelp("ctrl+f", "Search all"),
+		),
+		HideUnchanged: key.NewBinding(
+			key.WithKeys("."),
+			key.WithHelp(".", "Hide unchanged"),
+		),
+		OnlyModified: key.NewBinding(
+			key.WithKeys("M"),
+			key.WithHelp("M", "Only modified"),
+		),
+		Hash: key.NewBinding(
+			key.WithKeys("#"),
+			key.WithHelp("#", "Compare content"),
+		),
+		Diff: key.NewBinding(
+			key.WithKeys("D"),
+			key.WithHelp("D", "Diff file"),
+		),
+		Preview: key.NewBinding(
+			key.WithKeys("p"),
+			key.WithHelp("p", "Preview"),
+		),
+		Restore: key.NewBinding(
+			key.WithKeys("R"),
+			key.WithHelp("R", "Restore"),
+		),
+		Mark: key.NewBinding(
+			key.WithKeys("x"),
+			key.WithHelp("x", "Mark to exclude"),
+		),
+		Exclude: key.NewBinding(
+			key.WithKeys("X"),
+			key.WithHelp("X", "Exclude rules"),
+		),
+		Threshold: key.NewBinding(
+			key.WithKeys("t"),
+			key.WithHelp("t", "Set threshold"),
+		),
+		Confirm: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "Confirm"),
+		),
+		Cancel: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "Cancel"),
+		),
+		ResultUp: key.NewBinding(
+			key.WithKeys("up", "ctrl+p"),
+			key.WithHelp("up", "Previous result"),
+		),
+		ResultDown: key.NewBinding(
+			key.WithKeys("down", "ctrl+n"),
+			key.WithHelp("down", "Next result"),
+		),
+		CrumbLeft: key.NewBinding(
+			key.WithKeys("h", "left"),
+			key.WithHelp("h/left", "Previous parent"),
+		),
+		CrumbRight: key.NewBinding(
+			key.WithKeys("l", "right"),
+			key.WithHelp("l/right", "Next parent"),
+		),
+		Export: key.NewBinding(
+			key.WithKeys("e"),
+			key.WithHelp("e", "Export"),
+		),
 	}
 }
@@ -1,33 +1,30 @@
 package compare
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"math"
 	"os"
-	"path/filepath"
-	"sort"
 	"strings"
 
 	"gestic/models/compare/clip"
+	"gestic/models/compare/diffview"
+	"gestic/models/compare/exclude"
+	"gestic/models/compare/restore"
 	"gestic/restic"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbletea"
-	"github.com/dustin/go-humanize"
+	"github.com/charmbracelet/lipgloss"
 )
 
 const MaxColSize = 6
 const ViewportHeight = 12
 
-type Row struct {
-	dirA    *restic.DirData
-	dirB    *restic.DirData
-	absDiff uint64
-	diff    int
-}
-
 type Model struct {
 	prevModel tea.Model
 	clipModel tea.Model
@@ -36,18 +33,45 @@ type Model struct {
 	width     int
 	height    int
 
+	session  *session
+	options  Options
 	metadata restic.SnapshotsMetadata
-	rows     []Row
+	node     *Node
+	rows     []*Node
 	table    table.Model
+	offset   int // First visible row of the table
+	status   string
+
+	hidden         Hidden // Rows hidden by the options
+	overwrite      string // Export file to overwrite if asked again
+	filterInput    textinput.Model
+	filter         string // Pattern applied to the rows
+	thresholdInput textinput.Model
+	searchInput    textinput.Model
+	searching      bool
+	searchResults  []*Node
+	searchCursor   int
+
+	selectingCrumb bool
+	crumbCursor    int
+}
+
+// ExportDoneMsg is sent when the current directory was exported to a file
+type ExportDoneMsg struct {
+	Path  string
+	Count int
+	Err   error
 }
 
-func InitialModel(prevModel tea.Model, width, height int, dirNew, dirOld *restic.DirData, metadata restic.SnapshotsMetadata) *Model {
+func (ExportDoneMsg) Background() {}
+
+func InitialModel(prevModel tea.Model, width, height int, node *Node, metadata restic.SnapshotsMetadata, options Options) *Model {
 	columns := []table.Column{
 		{Title: "New", Width: 20},
 		{Title: "Old", Width: 20},
 		{Title: "Diff", Width: 10},
+		{Title: "Files", Width: 10},
 	}
-	rows := CreateRows(dirNew, dirOld, metadata)
 	m := Model{
 		prevModel: prevModel,
 		clipModel: clip.InitialModel(),
@@ -55,16 +79,25 @@ func InitialModel(prevModel tea.Model, width, height int, dirNew, dirOld *restic
 		keyMap:    DefaultKeyMap(),
 		width:     width,
 		height:    height,
-		rows:      rows,
+		options:   options,
 		metadata:  metadata,
+		node:      node,
 		table: table.New(
 			table.WithColumns(columns),
 			table.WithFocused(true),
 			table.WithHeight(ViewportHeight),
 			table.WithStyles(tableStyles),
 		),
+		filterInput:    newInput("/"),
+		searchInput:    newInput("Search: "),
+		thresholdInput: newInput("Threshold: "),
+	}
+	m.session = newSession()
+	if prev, ok := prevModel.(*Model); ok {
+		m.session = prev.session
 	}
-	m = *m.updateTable(-1)
+	m.refreshRows(nil)
+	m.restoreState()
 	return &m
 }
 
@@ -73,31 +106,72 @@ func (m *Model) Init() tea.Cmd {
 		tea.ClearScreen,
 		func() tea.Msg { return tea.WindowSizeMsg{Width: m.width, Height: m.height} },
 		m.updateClipboardCmd,
+		m.previewCmd(),
 	)
 }
 
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := m.update(msg)
+	if next != m {
+		return next, cmd
+	}
+	// Any update can change the selected row
+	return m, tea.Batch(cmd, m.previewCmd())
+}
+
+func (m *Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
-		c1Width := int(math.Floor(float64(m.width) * 0.4))
-		c2Width := int(math.Ceil(float64(m.width) * 0.4))
-		c3Width := m.width - c1Width - c2Width
-
-		columns := []table.Column{
-			{Title: fmt.Sprintf("--- New (%s) ---", m.metadata.NewerId), Width: c1Width},
-			{Title: fmt.Sprintf("--- Old (%s) ---", m.metadata.OlderId), Width: c2Width},
-			{Title: "---  Diff ---", Width: c3Width},
-		}
+		m.resizeTable()
+		return m.updateTable(m.table.Cursor()), nil
 
-		m.table.SetColumns(columns)
-		m.table.SetHeight(ViewportHeight)
+	case PreviewMsg:
+		// Only redraw, the preview is stored by previewCmd
+		return m, nil
 
-		return m.updateTable(m.table.Cursor()), nil
+	case ExportDoneMsg:
+		if errors.Is(msg.Err, fs.ErrExist) {
+			m.status = fmt.Sprintf("%s already exists, press e again to overwrite it", msg.Path)
+			m.overwrite = msg.Path
+		} else if msg.Err != nil {
+			m.status = fmt.Sprintf("Export failed: %v", msg.Err)
+		} else {
+			m.status = fmt.Sprintf("Exported %d entries to %s", msg.Count, msg.Path)
+		}
+		return m, nil
+
+	case HashDoneMsg:
+		applyHashes(msg.Node, msg.Hashes)
+		m.refreshRows(m.selectedNode())
+		if msg.Err != nil {
+			m.status = fmt.Sprintf("Content comparison failed: %v", msg.Err)
+		} else {
+			m.status = fmt.Sprintf("Compared the content of %d files: %d modified", len(msg.Hashes)/2, countModified(msg.Node))
+		}
+		return m, nil
 
 	case tea.KeyMsg:
+		// Text inputs get all the keys
+		if m.filterInput.Focused() {
+			return m.updateFilter(msg)
+		}
+		if m.searching {
+			return m.updateSearch(msg)
+		}
+		if m.thresholdInput.Focused() {
+			return m.updateThreshold(msg)
+		}
+		if m.selectingCrumb {
+			return m.updateBreadcrumb(msg)
+		}
+
+		// Overwriting is only confirmed by the next key
+		overwrite := m.overwrite
+		m.overwrite = ""
+
 		switch {
 		case key.Matches(msg, m.keyMap.Quit):
 			return m, tea.Quit
@@ -106,29 +180,112 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.help.ShowAll = !m.help.ShowAll
 			return m, nil
 
+		case key.Matches(msg, m.keyMap.Sort):
+			m.setSort(m.options.Sort.Next())
+			return m, m.updateClipboardCmd
+
+		case key.Matches(msg, m.keyMap.HideUnchanged):
+			m.options.HideUnchanged = !m.options.HideUnchanged
+			m.refreshRows(m.selectedNode())
+			return m, m.updateClipboardCmd
+
+		case key.Matches(msg, m.keyMap.OnlyModified):
+			m.options.OnlyModified = !m.options.OnlyModified
+			m.refreshRows(m.selectedNode())
+			return m, m.updateClipboardCmd
+
+		case key.Matches(msg, m.keyMap.Hash):
+			n := m.selectedNode()
+			if n == nil {
+				return m, nil
+			}
+			m.status = "Comparing content..."
+			return m, m.hashCmd(n)
+
+		case key.Matches(msg, m.keyMap.Diff):
+			n := m.selectedNode()
+			if n == nil || n.New == nil || n.Old == nil || n.New.IsDir || n.Old.IsDir {
+				m.status = "Select a file found in both snapshots to see its diff"
+				return m, nil
+			}
+			diffModel := diffview.InitialModel(m, m.width, m.height, m.metadata, "/"+n.RelPath, n.New, n.Old)
+			return diffModel, diffModel.Init()
+
+		case key.Matches(msg, m.keyMap.Restore):
+			n := m.selectedNode()
+			if n == nil {
+				return m, nil
+			}
+			restoreModel := restore.InitialModel(m, m.width, m.height, m.metadata, "/"+n.RelPath, n.New, n.Old)
+			return restoreModel, restoreModel.Init()
+
+		case key.Matches(msg, m.keyMap.Mark):
+			n := m.selectedNode()
+			if n == nil {
+				return m, nil
+			}
+			m.toggleMark(n)
+			m.updateTable(m.table.Cursor() + 1)
+			return m, m.updateClipboardCmd
+
+		case key.Matches(msg, m.keyMap.Exclude):
+			rules := excludeRules(m.node.Root(), m.session.marked)
+			excludeModel := exclude.InitialModel(m, m.width, m.height, rules, m.options.ExcludeFile)
+			return excludeModel, excludeModel.Init()
+
+		case key.Matches(msg, m.keyMap.Preview):
+			m.options.Preview = !m.options.Preview
+			m.resizeTable()
+			return m.updateTable(m.table.Cursor()), nil
+
+		case key.Matches(msg, m.keyMap.Threshold):
+			m.thresholdInput.SetValue(m.options.Threshold.String())
+			m.thresholdInput.CursorEnd()
+			return m, m.thresholdInput.Focus()
+
+		case key.Matches(msg, m.keyMap.Filter):
+			return m, m.filterInput.Focus()
+
+		case key.Matches(msg, m.keyMap.Cancel):
+			if m.filter != "" {
+				m.setFilter("")
+				return m, m.updateClipboardCmd
+			}
+			return m, nil
+
+		case key.Matches(msg, m.keyMap.Search):
+			m.searching = true
+			m.searchInput.Reset()
+			m.searchResults = nil
+			m.searchCursor = 0
+			return m, m.searchInput.Focus()
+
+		case key.Matches(msg, m.keyMap.Export):
+			m.status = "Exporting..."
+			return m, m.exportCmd(overwrite == m.exportName())
+
 		case key.Matches(msg, m.keyMap.NextDir):
-			nextNewDir := m.rows[m.table.Cursor()].dirA
+			nextNode := m.selectedNode()
 			// Don't try to advance if is an empty directory or a file
-			if len(nextNewDir.Children) == 0 {
+			if nextNode == nil || len(nextNode.Children) == 0 {
 				return m, nil
 			}
-			nextOldDir := m.rows[m.table.Cursor()].dirB
-			nextModel := InitialModel(m, m.width, m.height, nextNewDir, nextOldDir, m.metadata)
+			m.saveState()
+			nextModel := InitialModel(m, m.width, m.height, nextNode, m.metadata, m.options)
 			return nextModel, nextModel.Init()
 
 		case key.Matches(msg, m.keyMap.PrevDir):
-			// Notifies if the window have changed size
-
-			// I am not sure if this is necessary at all
-			// The idea is to preserve the window dimensions
-			// when navigating back, since the parent model
-			// does not know about possible changes
-			// Lets keep it for now
-			if m.prevModel != nil {
-				return m.prevModel, func() tea.Msg {
-					return tea.WindowSizeMsg{Width: m.width, Height: m.height}
-				}
+			if m.node.Parent != nil {
+				return m.goBackTo(m.node.Parent)
 			}
+
+		case key.Matches(msg, m.keyMap.Root):
+			return m.goBackTo(m.node.Root())
+
+		case key.Matches(msg, m.keyMap.Breadcrumb):
+			m.selectingCrumb = true
+			m.crumbCursor = max(len(m.crumbs())-2, 0)
+			return m, nil
 		}
 	}
 
@@ -141,6 +298,7 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	// We trigger an update only if the cursor changes
 	if m.table.Cursor() != oldCursor {
+		m.trackOffset()
 		cmds = append(cmds, m.updateClipboardCmd)
 	}
 
@@ -153,7 +311,18 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m *Model) View() string {
 	var output strings.Builder
 
-	output.WriteString(m.table.View())
+	output.WriteString(m.breadcrumbView())
+	output.WriteString("\n")
+	output.WriteString(m.headerView())
+	output.WriteString("\n")
+	switch {
+	case m.searching:
+		output.WriteString(m.searchView())
+	case m.options.Preview:
+		output.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, m.tableView(), m.previewView(m.width-m.tableWidth())))
+	default:
+		output.WriteString(m.tableView())
+	}
 	output.WriteString(m.metadataView())
 	output.WriteString("\n")
 	output.WriteString(m.help.View(m.keyMap))
@@ -161,57 +330,192 @@ func (m *Model) View() string {
 	return output.String()
 }
 
+// tableView renders the table with the runes matching the filter
+// underlined. The table truncates the cells, so they are plain text
+// and the matches are underlined in its output.
+func (m *Model) tableView() string {
+	view := m.table.View()
+	if m.filter == "" {
+		return view
+	}
+	lines := strings.Split(view, "\n")
+	// The first line is the header
+	for i := 1; i < len(lines); i++ {
+		lines[i] = highlightRow(lines[i], m.table.Columns(), m.filter)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// tableWidth returns the width left to the table by the preview pane
+func (m *Model) tableWidth() int {
+	if m.options.Preview {
+		return int(math.Floor(float64(m.width) * 0.6))
+	}
+	return m.width
+}
+
+// resizeTable fits the columns to the width of the table
+func (m *Model) resizeTable() {
+	width := m.tableWidth()
+	c1Width := int(math.Floor(float64(width) * 0.35))
+	c2Width := int(math.Ceil(float64(width) * 0.35))
+	c3Width := int(math.Floor(float64(width) * 0.14))
+	c4Width := width - c1Width - c2Width - c3Width
+
+	columns := []table.Column{
+		{Title: fmt.Sprintf("--- New (%s) ---", m.metadata.NewerId), Width: c1Width},
+		{Title: fmt.Sprintf("--- Old (%s) ---", m.metadata.OlderId), Width: c2Width},
+		{Title: "---  Diff ---", Width: c3Width},
+		{Title: "--- Files ---", Width: c4Width},
+	}
+
+	m.table.SetColumns(columns)
+	m.table.SetHeight(ViewportHeight)
+}
+
+func (m *Model) headerView() string {
+	header := fmt.Sprintf("Sort: %s", m.options.Sort)
+	if m.options.HideUnchanged {
+		header += "  Unchanged: hidden"
+	}
+	if m.options.OnlyModified {
+		header += "  Only modified"
+	}
+	if len(m.session.marked) > 0 {
+		header += fmt.Sprintf("  Marked: %d", len(m.session.marked))
+	}
+	if m.thresholdInput.Focused() {
+		header += "  " + m.thresholdInput.View()
+	} else if !m.options.Threshold.IsZero() {
+		header += fmt.Sprintf("  Threshold: %s", m.options.Threshold)
+	}
+	if m.filterInput.Focused() {
+		header += "  " + m.filterInput.View()
+	} else if m.filter != "" {
+		header += fmt.Sprintf("  Filter: %s (%d/%d)", m.filter, len(m.rows), len(m.node.Children))
+	}
+	return headerStyle.Render(header)
+}
+
 func (m *Model) metadataView() string {
 	var output strings.Builder
-	output.WriteString("\n\n")
+	output.WriteString("\n")
+	output.WriteString(m.node.Stats.String())
+	output.WriteString("\n")
+	output.WriteString(m.status)
+	output.WriteString("\n")
 	output.WriteString(m.clipModel.View())
 	return output.String()
 }
 
 func (m *Model) updateTable(cursor int) *Model {
-	rows, err := generateStringSlice(m.rows)
+	rows, err := generateStringSlice(m.rows, m.session.marked)
 	if err != nil {
 		panic(err)
 	}
+	if m.hidden.Count > 0 {
+		rows = append(rows, table.Row{m.hidden.String(), "", "", ""})
+	}
 	m.table.SetRows(rows)
-	m.table.SetCursor(cursor)
+	m.setCursor(cursor)
 	return m
 }
 
-func (m *Model) updateClipboardCmd() tea.Msg {
+// setSort sorts the rows by mode, keeping the cursor on the same entry
+func (m *Model) setSort(mode SortMode) {
+	m.options.Sort = mode
+	m.refreshRows(m.selectedNode())
+}
 
-	// This is relative to user files
-	// E.g. /home/myuser/foo/bar
-	userPath := m.metadata.NewerFullPath
+// setOptions applies options to the rows, keeping the cursor on the same entry
+func (m *Model) setOptions(options Options) {
+	m.options = options
+	m.refreshRows(m.selectedNode())
+}
+
+// refreshRows sorts and filters the children of the current node.
+// The cursor stays on selected if it is still a row.
+func (m *Model) refreshRows(selected *Node) {
+	rows := filterNodes(SortNodes(m.node.Children, m.options.Sort), m.filter)
+	m.rows, m.hidden = hideRows(rows, m.node, m.options)
+
+	cursor := 0
+	for i, r := range m.rows {
+		if r == selected {
+			cursor = i
+			break
+		}
+	}
+	m.updateTable(cursor)
+}
+
+// selectedNode returns the node under the cursor, nil if there are no
+// rows or the cursor is on the hidden entries summary
+func (m *Model) selectedNode() *Node {
+	if m.table.Cursor() < 0 || m.table.Cursor() >= len(m.rows) {
+		return nil
+	}
+	return m.rows[m.table.Cursor()]
+}
+
+// exportName returns the file the current directory is exported to
+func (m *Model) exportName() string {
+	name := fmt.Sprintf("gestic-%s-%s", m.metadata.NewerId, m.metadata.OlderId)
+	if m.node.RelPath != "" {
+		name += "-" + strings.ReplaceAll(m.node.RelPath, "/", "_")
+	}
+	return name + "." + ExportExtension(m.options.ExportFormat)
+}
+
+// exportCmd writes the diff of the current directory to the working
+// directory. An existing file is only replaced if overwrite is set.
+func (m *Model) exportCmd(overwrite bool) tea.Cmd {
+	name, format := m.exportName(), m.options.ExportFormat
+	records := CreateRecords(m.node, 0)
+	return func() tea.Msg {
+		flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if !overwrite {
+			flags |= os.O_EXCL
+		}
+		f, err := os.OpenFile(name, flags, 0o644)
+		if err != nil {
+			return ExportDoneMsg{Path: name, Err: err}
+		}
+		defer f.Close()
+
+		count, err := Export(f, format, records)
+		return ExportDoneMsg{Path: name, Count: count, Err: err}
+	}
+}
+
+func (m *Model) updateClipboardCmd() tea.Msg {
+	row := m.selectedNode()
+	if row == nil {
+		return nil
+	}
 
 	// This is relative to the snapshots
 	// E.g.: /mnt/mountpoint/snapshots/DATE-TIME/home/myuser/foo/bar
-	newerSnapshotPath := m.rows[m.table.Cursor()].dirA.Path
-	olderSnapshotPath := m.rows[m.table.Cursor()].dirB.Path
-
-	// If a path exists only in one directory the other one will be "???"
-	// Since we can't know which one is valid, check the first one
-	// If it is not valid, then the second MUST BE VALID
-	validSnapshotPath := newerSnapshotPath
-	if validSnapshotPath == "???" {
-		validSnapshotPath = olderSnapshotPath
-		userPath = m.metadata.OlderFullPath
+	// If a path exists only in one snapshot the other one is empty
+	var newerSnapshotPath, olderSnapshotPath string
+	if row.New != nil {
+		newerSnapshotPath = row.New.Path
 	}
-	fileSystemPath, err := filepath.Rel(userPath, validSnapshotPath)
-	if err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "Could not determine relative path of %s to %s: %s\n", userPath, validSnapshotPath, err.Error())
-		os.Exit(1)
+	if row.Old != nil {
+		olderSnapshotPath = row.Old.Path
 	}
 
+	// This is relative to user files
+	// E.g. /home/myuser/foo/bar
 	return clip.UpdateClipboardMsg{
 		First:  newerSnapshotPath,
 		Second: olderSnapshotPath,
-		Third:  "/" + fileSystemPath,
+		Third:  "/" + row.RelPath,
 	}
 
 }
 
-func renderSizePath(size, path string, col1Length int, isDir bool) (string, error) {
+func renderSizePath(size, path string, col1Length int) (string, error) {
 	s := ""
 	if len(size) > col1Length {
 		return "", fmt.Errorf("Column is to short to fit string %s", size)
@@ -221,78 +525,40 @@ func renderSizePath(size, path string, col1Length int, isDir bool) (string, erro
 	return s, nil
 }
 
-func generateStringSlice(rows []Row) ([]table.Row, error) {
+// renderEntry renders a side of a row, empty if the entry is missing
+func renderEntry(d *restic.DirData, name string) (string, error) {
+	if d == nil {
+		return "", nil
+	}
+	return renderSizePath(d.SizeReadable, name, MaxColSize)
+}
+
+func generateStringSlice(rows []*Node, marked map[*Node]bool) ([]table.Row, error) {
 	var t []table.Row
 	for _, r := range rows {
-		signStr := "+"
-		if r.diff < 0 {
-			signStr = "-"
+		diffStr := r.DiffReadable()
+		if r.Status == StatusMoved || r.Status == StatusModified {
+			diffStr = r.Status
+		}
+		if marked[r] {
+			diffStr = "✗ " + diffStr
 		}
-		diffStr := fmt.Sprintf("%s%s", signStr, humanize.Bytes(r.absDiff))
-		newerStr, err := renderSizePath(r.dirA.SizeReadable, r.dirA.PathReadable, MaxColSize, r.dirA.IsDir)
+		var newName, oldName string
+		if r.New != nil {
+			newName = r.New.PathReadable
+		}
+		if r.Old != nil {
+			oldName = r.OldPathReadable()
+		}
+		newerStr, err := renderEntry(r.New, newName)
 		if err != nil {
 			return t, fmt.Errorf("can't generate table row for newStr: %w", err)
 		}
-		eqStr, err := renderSizePath(r.dirB.SizeReadable, r.dirB.PathReadable, MaxColSize, r.dirB.IsDir)
+		eqStr, err := renderEntry(r.Old, oldName)
 		if err != nil {
 			return t, fmt.Errorf("can't generate table row for eqStr: %w", err)
 		}
-		t = append(t, []string{newerStr, eqStr, diffStr})
+		t = append(t, []string{newerStr, eqStr, diffStr, r.CountReadable()})
 	}
 	return t, nil
 }
-
-func CreateRows(dirA, dirB *restic.DirData, metadata restic.SnapshotsMetadata) []Row {
-	dumbDir := restic.DirData{
-		Path:         "???",
-		PathReadable: "???",
-		Size:         0,
-	}
-
-	// Generate maps for each directory
-	mapA := make(map[string]*restic.DirData)
-	mapB := make(map[string]*restic.DirData)
-	for _, a := range dirA.Children {
-		p, err := filepath.Rel(metadata.NewerFullPath, a.Path)
-		if err != nil {
-			panic(err)
-		}
-		mapA[p] = a
-	}
-	for _, b := range dirB.Children {
-		p, err := filepath.Rel(metadata.OlderFullPath, b.Path)
-		if err != nil {
-			panic(err)
-		}
-		mapB[p] = b
-	}
-	var rows []Row
-	seen := make(map[string]bool)
-
-	// Generate unique entries for elements on A and B
-	for path, a := range mapA {
-		seen[path] = true
-		if b, ok := mapB[path]; ok {
-			diff := int(a.Size) - int(b.Size)
-			absDiff := uint64(math.Abs(float64(diff)))
-			rows = append(rows, Row{dirA: a, dirB: b, diff: diff, absDiff: absDiff})
-		} else {
-			diff := int(a.Size)
-			absDiff := uint64(math.Abs(float64(a.Size)))
-			rows = append(rows, Row{dirA: a, dirB: &dumbDir, diff: diff, absDiff: absDiff})
-		}
-	}
-	for path, b := range mapB {
-		if seen[path] {
-			continue
-		}
-		diff := -int(b.Size)
-		absDiff := uint64(math.Abs(float64(b.Size)))
-		rows = append(rows, Row{dirA: &dumbDir, dirB: b, diff: diff, absDiff: absDiff})
-	}
-	sort.Slice(rows, func(i, j int) bool {
-		return rows[i].diff > rows[j].diff
-	})
-
-	return rows
-}
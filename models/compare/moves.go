@@ -0,0 +1,260 @@
+package compare
+
+import (
+	"fmt"
+	"hash/fnv"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gestic/restic"
+)
+
+// MoveScope is where removed and added entries are paired as moves
+type MoveScope string
+
+const (
+	MovesOff  MoveScope = "off"
+	MovesDir  MoveScope = "dir"  // Entries of the same directory
+	MovesTree MoveScope = "tree" // Entries anywhere in the tree
+)
+
+// moveKey is what a removed and an added entry must share to be a move
+type moveKey struct {
+	size  int64
+	files int64
+	dirs  int64
+	isDir bool
+}
+
+func keyOf(d *restic.DirData) moveKey {
+	return moveKey{size: d.Size, files: d.Files, dirs: d.Dirs, isDir: d.IsDir}
+}
+
+// DetectMoves replaces the removed and added entries of the tree that
+// have the same size and number of files with a single moved entry, at
+// the new path. When several entries share a size, only the ones with
+// a unique fingerprint are paired.
+func DetectMoves(root *Node, scope MoveScope) {
+	switch scope {
+	case MovesDir:
+		detectDirMoves(root)
+	case MovesTree:
+		var removed, added []*Node
+		collectCandidates(root, &removed, &added)
+		pairMoves(removed, added)
+	default:
+		return
+	}
+	root.recount()
+}
+
+func detectDirMoves(n *Node) {
+	var removed, added []*Node
+	for _, c := range n.Children {
+		switch c.Status {
+		case StatusRemoved:
+			removed = append(removed, c)
+		case StatusAdded:
+			added = append(added, c)
+		}
+	}
+	pairMoves(removed, added)
+	for _, c := range n.Children {
+		detectDirMoves(c)
+	}
+}
+
+// collectCandidates appends the removed and added nodes under n,
+// including the ones inside removed or added directories
+func collectCandidates(n *Node, removed, added *[]*Node) {
+	for _, c := range n.Children {
+		switch c.Status {
+		case StatusRemoved:
+			*removed = append(*removed, c)
+		case StatusAdded:
+			*added = append(*added, c)
+		}
+		collectCandidates(c, removed, added)
+	}
+}
+
+// pairMoves pairs removed with added nodes, largest first. Nodes inside
+// an entry that was already paired are skipped.
+func pairMoves(removed, added []*Node) {
+	removedByKey := make(map[moveKey][]*Node)
+	addedByKey := make(map[moveKey][]*Node)
+	for _, r := range removed {
+		removedByKey[keyOf(r.Old)] = append(removedByKey[keyOf(r.Old)], r)
+	}
+	for _, a := range added {
+		addedByKey[keyOf(a.New)] = append(addedByKey[keyOf(a.New)], a)
+	}
+
+	sorted := make([]*Node, len(removed))
+	copy(sorted, removed)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Old.Size != sorted[j].Old.Size {
+			return sorted[i].Old.Size > sorted[j].Old.Size
+		}
+		return strings.Count(sorted[i].RelPath, "/") < strings.Count(sorted[j].RelPath, "/")
+	})
+
+	paired := make(map[*Node]bool)
+	free := func(nodes []*Node) []*Node {
+		var result []*Node
+		for _, n := range nodes {
+			if !insidePaired(n, paired) {
+				result = append(result, n)
+			}
+		}
+		return result
+	}
+	fingerprints := make(map[*restic.DirData]uint64)
+
+	for _, r := range sorted {
+		// Empty entries are all alike
+		if r.Old.Size == 0 || insidePaired(r, paired) {
+			continue
+		}
+		key := keyOf(r.Old)
+		match := pickMove(r, free(removedByKey[key]), free(addedByKey[key]), fingerprints)
+		if match == nil || containsPaired(r, paired) || containsPaired(match, paired) {
+			continue
+		}
+		paired[r] = true
+		paired[match] = true
+		applyMove(r, match)
+	}
+}
+
+// pickMove returns the added node r was moved to, or nil if there is
+// none or it's ambiguous
+func pickMove(r *Node, rivals, candidates []*Node, fingerprints map[*restic.DirData]uint64) *Node {
+	if len(candidates) == 0 {
+		return nil
+	}
+	if len(rivals) == 1 && len(candidates) == 1 {
+		return candidates[0]
+	}
+
+	fp := cachedFingerprint(r.Old, fingerprints)
+	sameRivals := 0
+	for _, rival := range rivals {
+		if cachedFingerprint(rival.Old, fingerprints) == fp {
+			sameRivals++
+		}
+	}
+	var match *Node
+	for _, c := range candidates {
+		if cachedFingerprint(c.New, fingerprints) != fp {
+			continue
+		}
+		if match != nil {
+			return nil
+		}
+		match = c
+	}
+	if sameRivals != 1 {
+		return nil
+	}
+	return match
+}
+
+func insidePaired(n *Node, paired map[*Node]bool) bool {
+	for p := n; p != nil; p = p.Parent {
+		if paired[p] {
+			return true
+		}
+	}
+	return false
+}
+
+// containsPaired reports if a node under n was already paired
+func containsPaired(n *Node, paired map[*Node]bool) bool {
+	for _, c := range n.Children {
+		if paired[c] || containsPaired(c, paired) {
+			return true
+		}
+	}
+	return false
+}
+
+func cachedFingerprint(d *restic.DirData, cache map[*restic.DirData]uint64) uint64 {
+	if fp, ok := cache[d]; ok {
+		return fp
+	}
+	fp := fingerprint(d)
+	cache[d] = fp
+	return fp
+}
+
+// fingerprint hashes the relative paths and sizes of the entries under
+// d. Files only have their name, as their content isn't read.
+func fingerprint(d *restic.DirData) uint64 {
+	h := fnv.New64a()
+	if !d.IsDir {
+		_, _ = h.Write([]byte(filepath.Base(d.Path)))
+		return h.Sum64()
+	}
+
+	var lines []string
+	var walk func(e *restic.DirData)
+	walk = func(e *restic.DirData) {
+		for _, c := range e.Children {
+			rel := strings.TrimPrefix(c.Path, d.Path)
+			lines = append(lines, fmt.Sprintf("%s %d", rel, c.Size))
+			walk(c)
+		}
+	}
+	walk(d)
+	sort.Strings(lines)
+	for _, l := range lines {
+		_, _ = h.Write([]byte(l))
+		_, _ = h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
+
+// applyMove replaces added with a moved node pairing both entries, and
+// removes removed from its parent
+func applyMove(removed, added *Node) {
+	parent := added.Parent
+	moved := buildNode(parent, added.Name, added.New, removed.Old)
+	moved.Status = StatusMoved
+	moved.MovedFrom = removed.RelPath
+
+	for i, c := range parent.Children {
+		if c == added {
+			parent.Children[i] = moved
+		}
+	}
+	oldParent := removed.Parent
+	for i, c := range oldParent.Children {
+		if c == removed {
+			oldParent.Children = append(oldParent.Children[:i], oldParent.Children[i+1:]...)
+			break
+		}
+	}
+	sort.SliceStable(parent.Children, func(i, j int) bool {
+		return parent.Children[i].Diff > parent.Children[j].Diff
+	})
+}
+
+// recount updates the stats and status of n and its subtree
+func (n *Node) recount() {
+	for _, c := range n.Children {
+		c.recount()
+	}
+	n.recountLevel()
+}
+
+// recountLevel updates the stats and status of n from its children
+func (n *Node) recountLevel() {
+	n.Stats = Stats{}
+	for _, c := range n.Children {
+		n.Stats.count(c.Status)
+		n.Stats.add(c.Stats)
+	}
+	n.Status = n.status()
+}
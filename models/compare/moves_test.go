@@ -0,0 +1,78 @@
+package compare
+
+import "testing"
+
+func TestDetectMoves(t *testing.T) {
+	newFiles := map[string]string{
+		"photos/2023/a.jpg": "jpeg data",
+		"docs/renamed.txt":  "some text",
+		"archive/b.bin":     "a larger file",
+		"archive/other":     "o",
+		"inbox/keep":        "k",
+		"empty-new":         "",
+	}
+	oldFiles := map[string]string{
+		"pictures/2023/a.jpg": "jpeg data",
+		"docs/original.txt":   "some text",
+		"inbox/b.bin":         "a larger file",
+		"archive/other":       "o",
+		"inbox/keep":          "k",
+		"empty-old":           "",
+	}
+
+	tests := []struct {
+		scope MoveScope
+		moved map[string]string // Moved entries by path, with the old path
+	}{
+		{MovesOff, map[string]string{}},
+		{MovesDir, map[string]string{"photos": "pictures", "docs/renamed.txt": "docs/original.txt"}},
+		{MovesTree, map[string]string{"photos": "pictures", "docs/renamed.txt": "docs/original.txt", "archive/b.bin": "inbox/b.bin"}},
+	}
+	for _, tt := range tests {
+		newer, older := loadTrees(t, newFiles, oldFiles)
+		tree := BuildTree(newer, older)
+		DetectMoves(tree, tt.scope)
+
+		moved := make(map[string]string)
+		var walk func(n *Node)
+		walk = func(n *Node) {
+			for _, c := range n.Children {
+				if c.Status == StatusMoved {
+					moved[c.RelPath] = c.MovedFrom
+				}
+				walk(c)
+			}
+		}
+		walk(tree)
+
+		if len(moved) != len(tt.moved) {
+			t.Errorf("%s: moved = %v, want %v", tt.scope, moved, tt.moved)
+			continue
+		}
+		for p, from := range tt.moved {
+			if moved[p] != from {
+				t.Errorf("%s: moved = %v, want %v", tt.scope, moved, tt.moved)
+				break
+			}
+		}
+		if tt.scope == MovesTree {
+			if tree.Stats.Moved != 3 || tree.Stats.Removed != 1 || tree.Stats.Added != 1 {
+				t.Errorf("%s: stats = %v, want 3 moved and the empty files kept", tt.scope, tree.Stats)
+			}
+		}
+	}
+}
+
+func TestDetectMovesAmbiguous(t *testing.T) {
+	// Two files of the same size with other names can't be paired
+	newer, older := loadTrees(t,
+		map[string]string{"c": "123", "d": "456"},
+		map[string]string{"a": "123", "b": "456"},
+	)
+	tree := BuildTree(newer, older)
+	DetectMoves(tree, MovesDir)
+
+	if tree.Stats.Moved != 0 || tree.Stats.Added != 2 || tree.Stats.Removed != 2 {
+		t.Errorf("stats = %v, want the entries left added and removed", tree.Stats)
+	}
+}
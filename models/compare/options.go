@@ -0,0 +1,13 @@
+package compare
+
+// Options configures the compare view
+type Options struct {
+	ExportFormat  string    // Format of the files written with the Export key
+	Sort          SortMode  // Order of the rows
+	HideUnchanged bool      // Hide rows without changes
+	Threshold     Threshold // Hide rows with a smaller absolute diff
+	OnlyModified  bool      // Show only the entries whose content changed
+	Moves         MoveScope // Where to pair removed and added entries as moves
+	Preview       bool      // Show the preview pane
+	ExcludeFile   string    // Exclude file the marked rows are written to
+}
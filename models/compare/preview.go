@@ -0,0 +1,200 @@
+package compare
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"gestic/restic"
+
+	"github.com/charmbracelet/bubbletea"
+	"github.com/dustin/go-humanize"
+)
+
+// previewBytes is the size read from the start of a previewed file
+const previewBytes = 64 * 1024
+
+// previewLines is the height of the preview pane
+const previewLines = ViewportHeight
+
+// PreviewMsg is sent when the start of a file was read for the preview.
+// It is already stored in the session.
+type PreviewMsg struct {
+	Entry *restic.DirData
+	Head  []byte
+	Err   error
+}
+
+func (PreviewMsg) Background() {}
+
+// previewEntry returns the entry shown in the preview pane, the newer
+// one if the file is in both snapshots, and if it's from the older
+// snapshot. Directories have no preview.
+func previewEntry(n *Node) (*restic.DirData, bool) {
+	if n == nil {
+		return nil, false
+	}
+	entry, older := n.New, false
+	if entry == nil {
+		entry, older = n.Old, true
+	}
+	if entry.IsDir {
+		return nil, false
+	}
+	return entry, older
+}
+
+// previewCmd reads the start of the selected file, unless it was read before
+func (m *Model) previewCmd() tea.Cmd {
+	if !m.options.Preview {
+		return nil
+	}
+	entry, older := previewEntry(m.selectedNode())
+	if entry == nil {
+		return nil
+	}
+	if !m.session.startPreview(entry) {
+		return nil
+	}
+
+	metadata, session := m.metadata, m.session
+	return func() tea.Msg {
+		preview := readPreview(metadata, entry, older)
+		session.endPreview(preview)
+		return preview
+	}
+}
+
+// readPreview reads the start of entry, from the older snapshot if older is set
+func readPreview(metadata restic.SnapshotsMetadata, entry *restic.DirData, older bool) PreviewMsg {
+	if metadata.Source == nil {
+		return PreviewMsg{Entry: entry, Err: fmt.Errorf("the snapshots can't be read")}
+	}
+	snapshot := metadata.Newer
+	if older {
+		snapshot = metadata.Older
+	}
+	r, err := metadata.Source.Open(snapshot, entry.Path)
+	if err != nil {
+		return PreviewMsg{Entry: entry, Err: err}
+	}
+	head, readErr := io.ReadAll(io.LimitReader(r, previewBytes))
+	// restic dump reports its errors only when the file is closed
+	if err := r.Close(); err != nil {
+		return PreviewMsg{Entry: entry, Err: err}
+	}
+	if readErr != nil {
+		return PreviewMsg{Entry: entry, Err: fmt.Errorf("can't read %s: %w", entry.Path, readErr)}
+	}
+	return PreviewMsg{Entry: entry, Head: head}
+}
+
+// previewView returns the preview pane of the selected entry
+func (m *Model) previewView(width int) string {
+	style := previewStyle.Width(max(width-2, 1)).Height(previewLines)
+	entry, _ := previewEntry(m.selectedNode())
+	if entry == nil {
+		return style.Render("No file selected")
+	}
+	preview, ok := m.session.preview(entry)
+	switch {
+	case !ok:
+		return style.Render("Loading...")
+	case preview.Err != nil:
+		return style.Render(fmt.Sprintf("Can't read the file: %v", preview.Err))
+	}
+	return style.Render(renderPreview(preview.Head, entry.Size, max(width-4, 1)))
+}
+
+// renderPreview describes the start of a file: the first lines of a
+// text, the dimensions of an image, or a hexdump
+func renderPreview(head []byte, size int64, width int) string {
+	mime := http.DetectContentType(head)
+	var output strings.Builder
+
+	if strings.HasPrefix(mime, "image/") {
+		fmt.Fprintf(&output, "%s, %s\n", mime, humanize.Bytes(uint64(size)))
+		if config, format, err := image.DecodeConfig(bytes.NewReader(head)); err == nil {
+			fmt.Fprintf(&output, "%s image, %dx%d pixels\n", strings.ToUpper(format), config.Width, config.Height)
+		} else {
+			output.WriteString("Unknown dimensions\n")
+		}
+		return output.String()
+	}
+
+	// Like diff, files with a NUL byte are binary
+	text := head
+	if int64(len(head)) < size {
+		// The read may have split the last rune
+		for len(text) > 0 && !utf8.Valid(text) && len(head)-len(text) < utf8.UTFMax {
+			text = text[:len(text)-1]
+		}
+	}
+	if bytes.IndexByte(head, 0) < 0 && utf8.Valid(text) {
+		lines := strings.Split(strings.ReplaceAll(string(text), "\t", "    "), "\n")
+		for i, l := range lines[:min(len(lines), previewLines)] {
+			if i > 0 {
+				output.WriteString("\n")
+			}
+			output.WriteString(truncate(l, width))
+		}
+		return output.String()
+	}
+
+	output.WriteString(mime + "\n")
+	output.WriteString(hexdump(head, width, previewLines-1))
+	return output.String()
+}
+
+// hexdump returns the first lines of the hexdump of b, with as many
+// bytes per line as fit in width
+func hexdump(b []byte, width, lines int) string {
+	// Offset, then 3 columns per byte in hex and 1 as a character
+	perLine := max((width-10)/4/4*4, 4)
+	var output strings.Builder
+	for line := 0; line < lines && line*perLine < len(b); line++ {
+		chunk := b[line*perLine : min((line+1)*perLine, len(b))]
+		fmt.Fprintf(&output, "%08x  %-*s %s\n", line*perLine, perLine*3, hexBytes(chunk), printable(chunk))
+	}
+	return output.String()
+}
+
+func hexBytes(b []byte) string {
+	var output strings.Builder
+	for i, c := range b {
+		if i > 0 {
+			output.WriteString(" ")
+		}
+		output.WriteString(hex.EncodeToString([]byte{c}))
+	}
+	return output.String()
+}
+
+// printable replaces the bytes that are not printable ASCII with dots
+func printable(b []byte) string {
+	output := make([]byte, len(b))
+	for i, c := range b {
+		if c < 32 || c > 126 {
+			c = '.'
+		}
+		output[i] = c
+	}
+	return string(output)
+}
+
+// truncate cuts s to width runes
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) > width {
+		return string(runes[:width])
+	}
+	return s
+}
@@ -0,0 +1,108 @@
+package compare
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	"gestic/restic"
+)
+
+func TestRenderPreview(t *testing.T) {
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewGray(image.Rect(0, 0, 2, 3))); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		head  []byte
+		size  int64
+		width int
+		want  string
+	}{
+		{"text", []byte("first\n\tsecond\nthird"), 19, 80, "first\n    second\nthird"},
+		{"truncated lines", []byte("a long line\nshort"), 17, 6, "a long\nshort"},
+		{"split rune", []byte("caf\xc3"), 100, 80, "caf"},
+		{"invalid text", []byte("caf\xc3"), 4, 30, "text/plain; charset=utf-8\n00000000  63 61 66 c3  caf.\n"},
+		{"binary", []byte("ab\x00c"), 4, 30, "application/octet-stream\n00000000  61 62 00 63  ab.c\n"},
+		{"image", img.Bytes(), int64(img.Len()), 80, "PNG image, 2x3 pixels"},
+	}
+	for _, tt := range tests {
+		got := renderPreview(tt.head, tt.size, tt.width)
+		if tt.name == "image" {
+			if !strings.HasPrefix(got, "image/png, ") || !strings.Contains(got, tt.want) {
+				t.Errorf("%s: preview = %q, want the type and %q", tt.name, got, tt.want)
+			}
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: preview = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHexdump(t *testing.T) {
+	data := []byte("0123456789abcdefghij")
+	tests := []struct {
+		width, lines int
+		want         string
+	}{
+		{30, 10, "00000000  30 31 32 33  0123\n00000004  34 35 36 37  4567\n00000008  38 39 61 62  89ab\n0000000c  63 64 65 66  cdef\n00000010  67 68 69 6a  ghij\n"},
+		{30, 2, "00000000  30 31 32 33  0123\n00000004  34 35 36 37  4567\n"},
+		{60, 2, "00000000  30 31 32 33 34 35 36 37 38 39 61 62  0123456789ab\n0000000c  63 64 65 66 67 68 69 6a              cdefghij\n"},
+	}
+	for _, tt := range tests {
+		if got := hexdump(data, tt.width, tt.lines); got != tt.want {
+			t.Errorf("hexdump(%d, %d) = %q, want %q", tt.width, tt.lines, got, tt.want)
+		}
+	}
+}
+
+// dumpSource reads the files of a directory like restic dump, which
+// reports its errors when the file is closed
+type dumpSource struct {
+	restic.DirSource
+	err error
+}
+
+type dumpFile struct {
+	io.ReadCloser
+	err error
+}
+
+func (f dumpFile) Close() error {
+	return errors.Join(f.ReadCloser.Close(), f.err)
+}
+
+func (src dumpSource) Open(s restic.Snapshot, path string) (io.ReadCloser, error) {
+	r, err := src.DirSource.Open(s, path)
+	if err != nil {
+		return nil, err
+	}
+	return dumpFile{ReadCloser: r, err: src.err}, nil
+}
+
+func TestReadPreview(t *testing.T) {
+	newer, _ := loadTrees(t, map[string]string{"a.txt": "content"}, map[string]string{"a.txt": ""})
+	entry := newer.Children[0]
+	failed := errors.New("restic dump failed")
+
+	tests := []struct {
+		source restic.SnapshotSource
+		head   string
+		err    error
+	}{
+		{dumpSource{}, "content", nil},
+		{dumpSource{err: failed}, "", failed},
+	}
+	for _, tt := range tests {
+		preview := readPreview(restic.SnapshotsMetadata{Source: tt.source}, entry, false)
+		if string(preview.Head) != tt.head || !errors.Is(preview.Err, tt.err) || (tt.err == nil) != (preview.Err == nil) {
+			t.Errorf("preview = %q, %v, want %q, %v", preview.Head, preview.Err, tt.head, tt.err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package restore
+
+import "github.com/charmbracelet/bubbles/key"
+
+type keymap struct {
+	Start    key.Binding
+	Snapshot key.Binding
+	Target   key.Binding
+	Conflict key.Binding
+	Back     key.Binding
+	Quit     key.Binding
+}
+
+func (k keymap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Start, k.Snapshot, k.Target, k.Conflict, k.Back}
+}
+
+func (k keymap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Start, k.Snapshot},
+		{k.Target, k.Conflict},
+		{k.Back, k.Quit},
+	}
+}
+
+// DefaultKeyMap returns the keys of the restore form
+func DefaultKeyMap() keymap {
+	return keymap{
+		Start: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "Restore"),
+		),
+		Snapshot: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "Switch snapshot"),
+		),
+		Target: key.NewBinding(
+			key.WithKeys("t"),
+			key.WithHelp("t", "Edit target"),
+		),
+		Conflict: key.NewBinding(
+			key.WithKeys("c"),
+			key.WithHelp("c", "Existing files"),
+		),
+		Back: key.NewBinding(
+			key.WithKeys("esc", "q"),
+			key.WithHelp("esc/q", "Back"),
+		),
+		Quit: key.NewBinding(
+			key.WithKeys("ctrl+c"),
+			key.WithHelp("ctrl+c", "quit"),
+		),
+	}
+}
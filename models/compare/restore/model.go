@@ -0,0 +1,331 @@
+package restore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gestic/models"
+	"gestic/restic"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dustin/go-humanize"
+)
+
+// Phases of the restore
+const (
+	phasePlan = iota
+	phaseRunning
+	phaseDone
+)
+
+// progressWidth is the width of the progress bar
+const progressWidth = 40
+
+// Model restores an entry of the compared snapshots. It shows what the
+// restore would do before running it.
+type Model struct {
+	prevModel tea.Model
+	help      help.Model
+	keyMap    keymap
+	width     int
+	height    int
+
+	metadata restic.SnapshotsMetadata
+	name     string // Path in the snapshots
+	newEntry *restic.DirData
+	oldEntry *restic.DirData
+	older    bool // Restore from the older snapshot
+
+	targetInput textinput.Model
+	conflict    restic.Conflict
+	plan        restic.RestorePlan
+
+	phase    int
+	updates  models.Updates
+	cancel   context.CancelFunc
+	stopping bool // Cancelled, waiting for the restore to stop
+	quitting bool // Quit once the restore stopped
+	progress restic.RestoreProgress
+	result   restic.RestoreResult
+	err      error
+}
+
+type progressMsg restic.RestoreProgress
+
+type doneMsg struct {
+	result restic.RestoreResult
+	err    error
+}
+
+// InitialModel returns the restore form of the entry name, found in
+// one or both snapshots of metadata. The target is the original path.
+func InitialModel(prevModel tea.Model, width, height int, metadata restic.SnapshotsMetadata, name string, newEntry, oldEntry *restic.DirData) *Model {
+	targetInput := textinput.New()
+	targetInput.Prompt = ""
+	targetInput.SetValue(name)
+
+	m := &Model{
+		prevModel:   prevModel,
+		help:        help.New(),
+		keyMap:      DefaultKeyMap(),
+		width:       width,
+		height:      height,
+		metadata:    metadata,
+		name:        name,
+		newEntry:    newEntry,
+		oldEntry:    oldEntry,
+		older:       oldEntry != nil,
+		targetInput: targetInput,
+		conflict:    restic.ConflictSkip,
+	}
+	m.updatePlan()
+	return m
+}
+
+func (m *Model) Init() tea.Cmd {
+	return tea.ClearScreen
+}
+
+// entry returns the entry and snapshot to restore from
+func (m *Model) entry() (*restic.DirData, restic.Snapshot, string) {
+	if m.older {
+		return m.oldEntry, m.metadata.Older, m.metadata.OlderId
+	}
+	return m.newEntry, m.metadata.Newer, m.metadata.NewerId
+}
+
+func (m *Model) target() string {
+	target, err := filepath.Abs(m.targetInput.Value())
+	if err != nil {
+		return m.targetInput.Value()
+	}
+	return target
+}
+
+// updatePlan runs the dry run of the restore
+func (m *Model) updatePlan() {
+	entry, _, _ := m.entry()
+	m.plan = restic.PlanRestore(entry, m.target())
+}
+
+// startCmd runs the restore in the background. The progress is sent
+// through m.updates, m.cancel stops it.
+func (m *Model) startCmd() tea.Cmd {
+	entry, snapshot, _ := m.entry()
+	source := m.metadata.Source
+	target, conflict := m.target(), m.conflict
+	updates := models.NewUpdates()
+	ctx, cancel := context.WithCancel(context.Background())
+	m.updates, m.cancel = updates, cancel
+	m.phase = phaseRunning
+
+	go func() {
+		result, err := source.Restore(ctx, snapshot, entry, target, conflict, func(p restic.RestoreProgress) {
+			updates.Progress(progressMsg(p))
+		})
+		// The screen waits for the restore to stop after a cancel
+		updates <- doneMsg{result: result, err: err}
+	}()
+	return updates.Listen()
+}
+
+func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		return m, nil
+
+	case progressMsg:
+		m.progress = restic.RestoreProgress(msg)
+		return m, m.updates.Listen()
+
+	case doneMsg:
+		m.cancel()
+		m.phase = phaseDone
+		m.result = msg.result
+		m.err = msg.err
+		if m.quitting {
+			return m, tea.Quit
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		if m.phase == phaseRunning {
+			return m.updateRunning(msg)
+		}
+		if key.Matches(msg, m.keyMap.Quit) {
+			return m, tea.Quit
+		}
+		if m.targetInput.Focused() {
+			return m.updateTarget(msg)
+		}
+
+		switch {
+		case key.Matches(msg, m.keyMap.Back):
+			return m.prevModel, func() tea.Msg {
+				return tea.WindowSizeMsg{Width: m.width, Height: m.height}
+			}
+
+		case m.phase == phaseDone:
+			return m, nil
+
+		case key.Matches(msg, m.keyMap.Start):
+			if m.metadata.Source == nil {
+				m.err = fmt.Errorf("the snapshots can't be read")
+				return m, nil
+			}
+			return m, m.startCmd()
+
+		case key.Matches(msg, m.keyMap.Snapshot):
+			if m.newEntry != nil && m.oldEntry != nil {
+				m.older = !m.older
+				m.updatePlan()
+			}
+			return m, nil
+
+		case key.Matches(msg, m.keyMap.Conflict):
+			m.conflict = m.conflict.Next()
+			return m, nil
+
+		case key.Matches(msg, m.keyMap.Target):
+			m.targetInput.CursorEnd()
+			return m, m.targetInput.Focus()
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.targetInput, cmd = m.targetInput.Update(msg)
+	return m, tea.Batch(cmd, models.Forward(m.prevModel, msg))
+}
+
+// updateRunning handles the keys during the restore. Back cancels it
+// and quit also leaves once it stopped.
+func (m *Model) updateRunning(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keyMap.Quit):
+		m.quitting = true
+	case !key.Matches(msg, m.keyMap.Back):
+		return m, nil
+	}
+	m.stopping = true
+	m.cancel()
+	return m, nil
+}
+
+// updateTarget handles the keys while the target input has focus
+func (m *Model) updateTarget(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.targetInput.Blur()
+		m.updatePlan()
+		return m, nil
+	case tea.KeyEsc:
+		m.targetInput.Blur()
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.targetInput, cmd = m.targetInput.Update(msg)
+	return m, cmd
+}
+
+func (m *Model) View() string {
+	var output strings.Builder
+	entry, snapshot, id := m.entry()
+
+	output.WriteString(headerStyle.Render(fmt.Sprintf("Restore: %s", m.name)))
+	output.WriteString("\n\n")
+
+	snapshotStr := fmt.Sprintf("%s (%s)", id, snapshot.Date.Format("2006-01-02 15:04:05"))
+	if m.newEntry != nil && m.oldEntry != nil {
+		snapshotStr += "  [s to switch]"
+	}
+	fmt.Fprintf(&output, "%s %s\n", labelStyle.Render("Snapshot:"), snapshotStr)
+	fmt.Fprintf(&output, "%s %s\n", labelStyle.Render("Target:  "), m.targetInput.View())
+	fmt.Fprintf(&output, "%s %s\n\n", labelStyle.Render("Existing:"), m.conflict)
+
+	switch m.phase {
+	case phasePlan:
+		output.WriteString(m.planView(entry))
+	case phaseRunning:
+		output.WriteString(m.progressView())
+	case phaseDone:
+		if errors.Is(m.err, context.Canceled) {
+			fmt.Fprintf(&output, "Restore cancelled, %d files were restored.\n", m.result.Restored)
+		} else if m.err != nil {
+			fmt.Fprintf(&output, "Restore failed: %v\n", m.err)
+			fmt.Fprintf(&output, "%d files were restored before the error.\n", m.result.Restored)
+		} else {
+			fmt.Fprintf(&output, "Restored %d files (%s) to %s", m.result.Restored, humanize.Bytes(uint64(m.result.Bytes)), m.target())
+			if m.result.Skipped > 0 {
+				fmt.Fprintf(&output, ", skipped %d existing files", m.result.Skipped)
+			}
+			if m.result.Renamed > 0 {
+				fmt.Fprintf(&output, ", %d with a .restored suffix", m.result.Renamed)
+			}
+			output.WriteString(".\n")
+		}
+	}
+
+	output.WriteString("\n")
+	output.WriteString(m.help.View(m.keyMap))
+	return output.String()
+}
+
+// planView describes what the restore would do
+func (m *Model) planView(entry *restic.DirData) string {
+	var output strings.Builder
+	output.WriteString("Dry run: ")
+	if entry.IsDir {
+		fmt.Fprintf(&output, "%d files in %d directories, %s\n", m.plan.Files, m.plan.Dirs, humanize.Bytes(uint64(m.plan.Bytes)))
+	} else {
+		fmt.Fprintf(&output, "1 file, %s\n", humanize.Bytes(uint64(m.plan.Bytes)))
+	}
+
+	if m.plan.Existing > 0 {
+		var action string
+		switch m.conflict {
+		case restic.ConflictSkip:
+			action = "will be kept, skipping the restored ones"
+		case restic.ConflictOverwrite:
+			action = "will be overwritten"
+		default:
+			action = "will be kept, the restored ones get a .restored suffix"
+		}
+		output.WriteString(warningStyle.Render(fmt.Sprintf("%d files already exist and %s.", m.plan.Existing, action)))
+		output.WriteString("\n")
+	} else {
+		output.WriteString("No file exists in the target.\n")
+	}
+	if m.err != nil {
+		fmt.Fprintf(&output, "%v\n", m.err)
+	}
+	return output.String()
+}
+
+// progressView returns a progress bar of the running restore
+func (m *Model) progressView() string {
+	if m.stopping {
+		return "Cancelling the restore...\n"
+	}
+	total, done := m.progress.TotalBytes, m.progress.Bytes
+	if total == 0 {
+		total, done = m.progress.TotalFiles, m.progress.Files
+	}
+	filled := 0
+	if total > 0 {
+		filled = int(min(done*progressWidth/total, progressWidth))
+	}
+	return fmt.Sprintf("Restoring... [%s%s] %d/%d files, %s / %s, esc to cancel\n",
+		strings.Repeat("#", filled), strings.Repeat("-", progressWidth-filled),
+		m.progress.Files, m.progress.TotalFiles,
+		humanize.Bytes(uint64(m.progress.Bytes)), humanize.Bytes(uint64(m.progress.TotalBytes)))
+}
@@ -0,0 +1,13 @@
+package restore
+
+import "github.com/charmbracelet/lipgloss"
+
+var headerStyle = lipgloss.NewStyle().
+	Bold(true).
+	Padding(0, 1)
+
+var labelStyle = lipgloss.NewStyle().
+	Bold(true)
+
+var warningStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#fdbc4b"))
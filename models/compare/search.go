@@ -0,0 +1,151 @@
+package compare
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Number of results shown by the global search
+const searchLimit = 10
+
+func newInput(prompt string) textinput.Model {
+	input := textinput.New()
+	input.Prompt = prompt
+	return input
+}
+
+// updateFilter handles the keys while the filter input has focus
+func (m *Model) updateFilter(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keyMap.Confirm):
+		m.filterInput.Blur()
+		return m, nil
+	case key.Matches(msg, m.keyMap.Cancel):
+		m.filterInput.Blur()
+		m.setFilter("")
+		return m, m.updateClipboardCmd
+	}
+
+	var cmd tea.Cmd
+	m.filterInput, cmd = m.filterInput.Update(msg)
+	if m.filterInput.Value() != m.filter {
+		m.setFilter(m.filterInput.Value())
+		return m, tea.Batch(cmd, m.updateClipboardCmd)
+	}
+	return m, cmd
+}
+
+// setFilter narrows the rows to the ones matching pattern
+func (m *Model) setFilter(pattern string) {
+	m.filter = pattern
+	m.filterInput.SetValue(pattern)
+	m.refreshRows(m.selectedNode())
+}
+
+// updateSearch handles the keys while the global search is open
+func (m *Model) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keyMap.Confirm):
+		m.searching = false
+		m.searchInput.Blur()
+		if len(m.searchResults) == 0 {
+			return m, nil
+		}
+		return m.jumpTo(m.searchResults[m.searchCursor])
+	case key.Matches(msg, m.keyMap.Cancel):
+		m.searching = false
+		m.searchInput.Blur()
+		return m, nil
+	case key.Matches(msg, m.keyMap.ResultUp):
+		m.searchCursor = max(m.searchCursor-1, 0)
+		return m, nil
+	case key.Matches(msg, m.keyMap.ResultDown):
+		m.searchCursor = min(m.searchCursor+1, max(len(m.searchResults)-1, 0))
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.searchInput, cmd = m.searchInput.Update(msg)
+	m.searchResults = nil
+	m.searchCursor = 0
+	if pattern := m.searchInput.Value(); pattern != "" {
+		m.searchResults = searchTree(m.node.Root(), pattern, searchLimit)
+	}
+	return m, cmd
+}
+
+// rootModel returns the first compare model of the prevModel chain
+func (m *Model) rootModel() *Model {
+	root := m
+	for {
+		prev, ok := root.prevModel.(*Model)
+		if !ok {
+			return root
+		}
+		root = prev
+	}
+}
+
+// jumpTo opens the directory of target with the cursor on it. It
+// rebuilds the prevModel chain from the root model, so going back
+// passes through each parent directory.
+func (m *Model) jumpTo(target *Node) (tea.Model, tea.Cmd) {
+	m.saveState()
+	current := m.rootModel()
+	current.setFilter("")
+	current.refreshRows(nil)
+
+	started := false
+	for _, ancestor := range target.Ancestors() {
+		if !started {
+			started = ancestor == current.node
+			continue
+		}
+		current.selectNode(ancestor)
+		current.saveState()
+		current = InitialModel(current, m.width, m.height, ancestor, m.metadata, m.options)
+	}
+	current.selectNode(target)
+	return current, current.Init()
+}
+
+// selectNode moves the cursor to n, if it is one of the rows. The
+// filter restored for the directory is cleared if it hides n.
+func (m *Model) selectNode(n *Node) {
+	if m.filter != "" && !slices.Contains(m.rows, n) {
+		m.setFilter("")
+	}
+	for i, r := range m.rows {
+		if r == n {
+			m.setCursor(i)
+			return
+		}
+	}
+}
+
+func (m *Model) searchView() string {
+	var output strings.Builder
+	output.WriteString(m.searchInput.View())
+	output.WriteString("\n")
+
+	for i := 0; i < ViewportHeight; i++ {
+		if i < len(m.searchResults) {
+			n := m.searchResults[i]
+			matched, _, _ := fuzzyMatch(m.searchInput.Value(), n.RelPath)
+			line := fmt.Sprintf("%s /%s", n.DiffReadable(), highlight(n.RelPath, matched, matchStyle.Render))
+			if i == m.searchCursor {
+				line = "> " + line
+			} else {
+				line = "  " + line
+			}
+			output.WriteString(line)
+		}
+		output.WriteString("\n")
+	}
+	return output.String()
+}
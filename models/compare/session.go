@@ -0,0 +1,129 @@
+package compare
+
+import (
+	"sync"
+
+	"gestic/restic"
+)
+
+// viewState is the position in a directory when it was left
+type viewState struct {
+	cursor int
+	offset int // First visible row
+	sort   SortMode
+	filter string
+}
+
+// session keeps the view state of each visited directory, by
+// relative path, while the program runs. It is shared by all the
+// compare models of a comparison.
+type session struct {
+	states map[string]viewState
+	marked map[*Node]bool // Rows to exclude from the backups
+
+	// The previews are stored by the reads, even if the screen showing
+	// them was left before they ended
+	mu         sync.Mutex
+	previews   map[*restic.DirData]PreviewMsg // Start of the previewed files
+	previewing map[*restic.DirData]bool       // Previews being read
+}
+
+func newSession() *session {
+	return &session{
+		states:     make(map[string]viewState),
+		marked:     make(map[*Node]bool),
+		previews:   make(map[*restic.DirData]PreviewMsg),
+		previewing: make(map[*restic.DirData]bool),
+	}
+}
+
+// startPreview reports if entry has to be read for the preview, and
+// marks it as being read
+func (s *session) startPreview(entry *restic.DirData) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.previews[entry]; ok || s.previewing[entry] {
+		return false
+	}
+	s.previewing[entry] = true
+	return true
+}
+
+// endPreview stores the read start of a file
+func (s *session) endPreview(preview PreviewMsg) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.previews[preview.Entry] = preview
+	delete(s.previewing, preview.Entry)
+}
+
+// preview returns the start of entry, if it was read
+func (s *session) preview(entry *restic.DirData) (PreviewMsg, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	preview, ok := s.previews[entry]
+	return preview, ok
+}
+
+// saveState stores the position in the current directory
+func (m *Model) saveState() {
+	m.session.states[m.node.RelPath] = viewState{
+		cursor: m.table.Cursor(),
+		offset: m.offset,
+		sort:   m.options.Sort,
+		filter: m.filter,
+	}
+}
+
+// restoreState moves to the position the current directory had
+// when it was left, if it was visited before
+func (m *Model) restoreState() {
+	state, ok := m.session.states[m.node.RelPath]
+	if !ok {
+		return
+	}
+	m.options.Sort = state.sort
+	m.filter = state.filter
+	m.filterInput.SetValue(state.filter)
+	m.refreshRows(nil)
+	m.offset = state.offset
+	m.setCursor(state.cursor)
+}
+
+// setCursor moves the cursor keeping it inside the visible rows.
+// table.SetCursor doesn't update the scroll position, so the moves
+// are replayed from the top until the first visible row is m.offset.
+func (m *Model) setCursor(cursor int) {
+	rowCount := len(m.table.Rows())
+	// The table has no cursor to move without rows
+	if rowCount == 0 {
+		m.offset = 0
+		return
+	}
+	visible := max(m.table.Height(), 1)
+	cursor = max(min(cursor, rowCount-1), 0)
+
+	m.offset = min(m.offset, cursor)
+	m.offset = max(m.offset, cursor-visible+1, 0)
+
+	m.table.GotoTop()
+	last := max(min(m.offset+visible-1, rowCount-1), cursor)
+	for m.table.Cursor() < last {
+		m.table.MoveDown(1)
+	}
+	for m.table.Cursor() > cursor {
+		m.table.MoveUp(1)
+	}
+}
+
+// trackOffset updates the first visible row after the table moved the cursor
+func (m *Model) trackOffset() {
+	visible := max(m.table.Height(), 1)
+	cursor := m.table.Cursor()
+	if cursor < m.offset {
+		m.offset = cursor
+	}
+	if cursor > m.offset+visible-1 {
+		m.offset = cursor - visible + 1
+	}
+}
@@ -0,0 +1,104 @@
+package compare
+
+import (
+	"fmt"
+	"testing"
+
+	"gestic/restic"
+)
+
+// sessionTree returns a tree with the directory "dir" holding count
+// added files, f00 being the smallest
+func sessionTree(t *testing.T, count int) *Node {
+	t.Helper()
+	files := make(map[string]string)
+	for i := range count {
+		files[fmt.Sprintf("dir/f%02d", i)] = fmt.Sprintf("%0*d", i+1, 0)
+	}
+	newer, older := loadTrees(t, files, map[string]string{"dir/f00": "0"})
+	return BuildTree(newer, older)
+}
+
+func TestSetCursor(t *testing.T) {
+	dir := child(t, sessionTree(t, 30), "dir")
+	m := InitialModel(nil, 100, 40, dir, restic.SnapshotsMetadata{}, Options{Sort: SortByName})
+	visible := m.table.Height()
+
+	tests := []struct {
+		name                   string
+		offset, cursor         int
+		wantOffset, wantCursor int
+	}{
+		{"visible", 0, 5, 0, 5},
+		{"below", 0, 20, 20 - visible + 1, 20},
+		{"above", 15, 10, 10, 10},
+		{"inside", 10, 12, 10, 12},
+		{"after the last row", 0, 100, 30 - visible, 29},
+		{"before the first row", 5, -3, 0, 0},
+	}
+	for _, tt := range tests {
+		m.offset = tt.offset
+		m.setCursor(tt.cursor)
+		if m.offset != tt.wantOffset || m.table.Cursor() != tt.wantCursor {
+			t.Errorf("%s: offset %d, cursor %d, want %d, %d", tt.name, m.offset, m.table.Cursor(), tt.wantOffset, tt.wantCursor)
+		}
+		// The table scrolls with the cursor, the first visible row must
+		// stay at the offset when it moves up to it
+		for m.table.Cursor() > m.offset {
+			m.table.MoveUp(1)
+			m.trackOffset()
+		}
+		if m.offset != tt.wantOffset {
+			t.Errorf("%s: the table scrolled to %d, want %d", tt.name, m.offset, tt.wantOffset)
+		}
+	}
+
+	empty := InitialModel(nil, 100, 40, child(t, dir, "f00"), restic.SnapshotsMetadata{}, Options{})
+	empty.offset = 3
+	empty.setCursor(2)
+	if empty.offset != 0 || empty.table.Cursor() != 0 {
+		t.Errorf("without rows: offset %d, cursor %d, want 0, 0", empty.offset, empty.table.Cursor())
+	}
+}
+
+func TestRestoreState(t *testing.T) {
+	tree := sessionTree(t, 30)
+	dir := child(t, tree, "dir")
+	root := InitialModel(nil, 100, 40, tree, restic.SnapshotsMetadata{}, Options{})
+
+	tests := []struct {
+		name   string
+		sort   SortMode
+		filter string
+		cursor int
+		offset int
+		want   string // Name of the selected row
+	}{
+		{"sorted", SortByName, "", 25, 20, "f25"},
+		{"by size", SortByDiff, "", 3, 0, "f26"},
+		{"filtered", SortByName, "f1", 4, 2, "f13"},
+	}
+	for _, tt := range tests {
+		m := InitialModel(root, 100, 40, dir, restic.SnapshotsMetadata{}, Options{})
+		m.options.Sort = tt.sort
+		m.setFilter(tt.filter)
+		m.offset = tt.offset
+		m.setCursor(tt.cursor)
+		m.saveState()
+
+		again := InitialModel(root, 100, 40, dir, restic.SnapshotsMetadata{}, Options{})
+		if again.options.Sort != tt.sort || again.filter != tt.filter || again.filterInput.Value() != tt.filter {
+			t.Errorf("%s: sort %v and filter %q, want %v and %q", tt.name, again.options.Sort, again.filter, tt.sort, tt.filter)
+		}
+		if again.table.Cursor() != tt.cursor || again.offset != tt.offset || again.selectedNode().Name != tt.want {
+			t.Errorf("%s: cursor %d, offset %d on %s, want %d, %d on %s", tt.name,
+				again.table.Cursor(), again.offset, again.selectedNode().Name, tt.cursor, tt.offset, tt.want)
+		}
+	}
+
+	// Another directory starts from the top
+	other := InitialModel(root, 100, 40, tree, restic.SnapshotsMetadata{}, Options{})
+	if other.table.Cursor() != 0 || other.offset != 0 || other.filter != "" {
+		t.Errorf("unvisited directory: cursor %d, offset %d, filter %q, want the top", other.table.Cursor(), other.offset, other.filter)
+	}
+}
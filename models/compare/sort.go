@@ -0,0 +1,108 @@
+package compare
+
+import "sort"
+
+// SortMode is the order of the rows in the compare table
+type SortMode int
+
+const (
+	SortByDiff    SortMode = iota // Signed size diff, descending
+	SortByAbsDiff                 // Absolute size diff, descending
+	SortByNewSize                 // Size in the newer snapshot, descending
+	SortByOldSize                 // Size in the older snapshot, descending
+	SortByName                    // Name, ascending
+	SortByStatus                  // Added, removed, moved, changed, modified, then unchanged
+	SortByCount                   // Signed file count diff, descending
+)
+
+var sortModeNames = []string{"signed diff", "absolute diff", "new size", "old size", "name", "status", "file count diff"}
+
+// statusOrder is the position of each status when sorting by status
+var statusOrder = map[string]int{
+	StatusAdded:     0,
+	StatusRemoved:   1,
+	StatusMoved:     2,
+	StatusChanged:   3,
+	StatusModified:  4,
+	StatusUnchanged: 5,
+}
+
+func (s SortMode) String() string {
+	return sortModeNames[s]
+}
+
+// Next returns the mode after s, wrapping to the first one
+func (s SortMode) Next() SortMode {
+	return (s + 1) % SortMode(len(sortModeNames))
+}
+
+// SortNodes returns a copy of nodes sorted by mode. Ties keep the
+// order of nodes.
+func SortNodes(nodes []*Node, mode SortMode) []*Node {
+	var less func(a, b *Node) bool
+	switch mode {
+	case SortByAbsDiff:
+		less = func(a, b *Node) bool {
+			return a.AbsDiff > b.AbsDiff
+		}
+	case SortByNewSize:
+		less = func(a, b *Node) bool {
+			return newSize(a) > newSize(b)
+		}
+	case SortByOldSize:
+		less = func(a, b *Node) bool {
+			return oldSize(a) > oldSize(b)
+		}
+	case SortByName:
+		less = func(a, b *Node) bool {
+			return a.Name < b.Name
+		}
+	case SortByStatus:
+		less = func(a, b *Node) bool {
+			if a.Status != b.Status {
+				return statusOrder[a.Status] < statusOrder[b.Status]
+			}
+			return a.Diff > b.Diff
+		}
+	case SortByCount:
+		less = func(a, b *Node) bool {
+			if a.FilesDiff != b.FilesDiff {
+				return a.FilesDiff > b.FilesDiff
+			}
+			return a.DirsDiff > b.DirsDiff
+		}
+	default:
+		less = func(a, b *Node) bool {
+			return a.Diff > b.Diff
+		}
+	}
+
+	return SortStable(nodes, less)
+}
+
+// SortStable returns a copy of nodes sorted by less, keeping the order
+// of the equal ones
+func SortStable[N any](nodes []N, less func(a, b N) bool) []N {
+	sorted := make([]N, len(nodes))
+	copy(sorted, nodes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return less(sorted[i], sorted[j])
+	})
+	return sorted
+}
+
+// newSize returns the size in the newer snapshot, -1 if missing
+func newSize(n *Node) int64 {
+	if n.New == nil {
+		return -1
+	}
+	return n.New.Size
+}
+
+// oldSize returns the size in the older snapshot, -1 if missing
+func oldSize(n *Node) int64 {
+	if n.Old == nil {
+		return -1
+	}
+	return n.Old.Size
+}
@@ -17,3 +17,22 @@ var tableStyles = table.Styles{
 		// Background(lipgloss.Color("#fcfcfc")),
 	Cell: lipgloss.NewStyle().Padding(0, 1),
 }
+
+var headerStyle = lipgloss.NewStyle().
+	Bold(true).
+	Padding(0, 1)
+
+var matchStyle = lipgloss.NewStyle().
+	Underline(true)
+
+var crumbStyle = lipgloss.NewStyle().
+	Padding(0, 1)
+
+var crumbSelectedStyle = lipgloss.NewStyle().
+	Bold(true).
+	Foreground(lipgloss.Color("#232627")).
+	Background(lipgloss.Color("#fcfcfc"))
+
+var previewStyle = lipgloss.NewStyle().
+	Border(lipgloss.NormalBorder(), false, false, false, true).
+	PaddingLeft(1)
@@ -0,0 +1,144 @@
+package compare
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dustin/go-humanize"
+)
+
+// Threshold hides rows with a small absolute diff. It is either a size
+// or a percentage of the size of the directory being shown. Moved or
+// modified rows whose size didn't change are never hidden by it.
+type Threshold struct {
+	Bytes   uint64
+	Percent float64
+	text    string // As given by the user
+}
+
+// ParseThreshold reads a size like "1MiB" or "10 kB", or a percentage
+// like "0.5%". An empty string or "0" disables the threshold.
+func ParseThreshold(s string) (Threshold, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" {
+		return Threshold{}, nil
+	}
+	if strings.HasSuffix(s, "%") {
+		percent, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(s, "%")), 64)
+		if err != nil || percent < 0 {
+			return Threshold{}, fmt.Errorf("invalid percentage %q", s)
+		}
+		return Threshold{Percent: percent, text: s}, nil
+	}
+	bytes, err := humanize.ParseBytes(s)
+	if err != nil {
+		return Threshold{}, fmt.Errorf("invalid size %q", s)
+	}
+	return Threshold{Bytes: bytes, text: s}, nil
+}
+
+func (t Threshold) String() string {
+	switch {
+	case t.text != "":
+		return t.text
+	case t.Percent > 0:
+		return strconv.FormatFloat(t.Percent, 'f', -1, 64) + "%"
+	case t.Bytes > 0:
+		return humanize.IBytes(t.Bytes)
+	default:
+		return "0"
+	}
+}
+
+// IsZero reports if the threshold is disabled
+func (t Threshold) IsZero() bool {
+	return t.Bytes == 0 && t.Percent == 0
+}
+
+// limit returns the threshold in bytes for the children of parent
+func (t Threshold) limit(parent *Node) uint64 {
+	if t.Percent == 0 {
+		return t.Bytes
+	}
+	var size int64
+	if parent.New != nil {
+		size = parent.New.Size
+	}
+	if parent.Old != nil {
+		size = max(size, parent.Old.Size)
+	}
+	return uint64(float64(size) * t.Percent / 100)
+}
+
+// Hidden summarizes the rows hidden by the options
+type Hidden struct {
+	Count int
+	Diff  int64 // Sum of the signed diffs
+}
+
+func (h Hidden) String() string {
+	signStr := "+"
+	if h.Diff < 0 {
+		signStr = "-"
+	}
+	return fmt.Sprintf("%d entries hidden (%s%s total)", h.Count, signStr, humanize.Bytes(uint64(max(h.Diff, -h.Diff))))
+}
+
+// hideRows removes the children of parent that are unchanged, below
+// the threshold or not modified, if the options ask for it
+func hideRows(rows []*Node, parent *Node, options Options) ([]*Node, Hidden) {
+	var hidden Hidden
+	if !options.HideUnchanged && options.Threshold.IsZero() && !options.OnlyModified {
+		return rows, hidden
+	}
+
+	limit := options.Threshold.limit(parent)
+	var visible []*Node
+	for _, r := range rows {
+		unchanged := r.Status == StatusUnchanged
+		if (options.HideUnchanged && unchanged) || belowThreshold(r, limit) || (options.OnlyModified && !contentChanged(r)) {
+			hidden.Count++
+			hidden.Diff += r.Diff
+			continue
+		}
+		visible = append(visible, r)
+	}
+	return visible, hidden
+}
+
+// belowThreshold reports if the size of r changed by less than limit.
+// Moved and modified entries of the same size changed anyway.
+func belowThreshold(r *Node, limit uint64) bool {
+	if r.AbsDiff == 0 && (r.Status == StatusMoved || r.Status == StatusModified) {
+		return false
+	}
+	return r.AbsDiff < limit
+}
+
+// updateThreshold handles the keys while the threshold input has focus
+func (m *Model) updateThreshold(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keyMap.Confirm):
+		threshold, err := ParseThreshold(m.thresholdInput.Value())
+		if err != nil {
+			m.status = err.Error()
+			return m, nil
+		}
+		m.thresholdInput.Blur()
+		m.status = ""
+		m.options.Threshold = threshold
+		m.refreshRows(m.selectedNode())
+		return m, m.updateClipboardCmd
+	case key.Matches(msg, m.keyMap.Cancel):
+		m.thresholdInput.Blur()
+		m.status = ""
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.thresholdInput, cmd = m.thresholdInput.Update(msg)
+	return m, cmd
+}
@@ -0,0 +1,77 @@
+package compare
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseThreshold(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    Threshold
+		wantErr bool
+	}{
+		{s: "", want: Threshold{}},
+		{s: "0", want: Threshold{}},
+		{s: "1MiB", want: Threshold{Bytes: 1 << 20, text: "1MiB"}},
+		{s: " 10 kB ", want: Threshold{Bytes: 10000, text: "10 kB"}},
+		{s: "0.5%", want: Threshold{Percent: 0.5, text: "0.5%"}},
+		{s: "-1%", wantErr: true},
+		{s: "big", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseThreshold(tt.s)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseThreshold(%q) error = %v, want error %v", tt.s, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseThreshold(%q) = %+v, want %+v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestHideRows(t *testing.T) {
+	newer, older := loadTrees(t, map[string]string{
+		"same":    "0123456789",
+		"kept":    "0123456789",
+		"small":   "0123456789+",
+		"large":   "0123456789++++++++++++++++++++",
+		"added":   "0123456789",
+		"renamed": "abc",
+	}, map[string]string{
+		"same":     "0123456789",
+		"kept":     "0123456789",
+		"small":    "0123456789",
+		"large":    "0123456789",
+		"original": "abc",
+	})
+	tree := BuildTree(newer, older)
+	DetectMoves(tree, MovesDir)
+	modified := child(t, tree, "same")
+	modified.Status = StatusModified
+
+	tests := []struct {
+		name    string
+		options Options
+		visible []string
+		hidden  Hidden
+	}{
+		{"none", Options{}, []string{"added", "kept", "large", "renamed", "same", "small"}, Hidden{}},
+		{"unchanged", Options{HideUnchanged: true}, []string{"added", "large", "renamed", "same", "small"}, Hidden{Count: 1}},
+		{"bytes", Options{Threshold: Threshold{Bytes: 5}}, []string{"added", "large", "renamed", "same"}, Hidden{Count: 2, Diff: 1}},
+		{"percent", Options{Threshold: Threshold{Percent: 20}}, []string{"large", "renamed", "same"}, Hidden{Count: 3, Diff: 11}},
+		{"modified", Options{OnlyModified: true}, []string{"large", "same", "small"}, Hidden{Count: 3, Diff: 10}},
+	}
+	for _, tt := range tests {
+		rows, hidden := hideRows(tree.Children, tree, tt.options)
+		var names []string
+		for _, r := range rows {
+			names = append(names, r.Name)
+		}
+		slices.Sort(names)
+		if !slices.Equal(names, tt.visible) || hidden != tt.hidden {
+			t.Errorf("%s: %v and %+v, want %v and %+v", tt.name, names, hidden, tt.visible, tt.hidden)
+		}
+	}
+}
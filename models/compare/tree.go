@@ -0,0 +1,247 @@
+package compare
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"gestic/restic"
+
+	"github.com/dustin/go-humanize"
+)
+
+// Status of an entry between the two snapshots
+const (
+	StatusAdded     = "added"
+	StatusRemoved   = "removed"
+	StatusMoved     = "moved"
+	StatusChanged   = "changed"
+	StatusModified  = "modified" // Same size, but different content or metadata
+	StatusUnchanged = "unchanged"
+)
+
+// Node is an entry of the diff tree of two snapshots.
+type Node struct {
+	Name      string          // Name relative to the parent
+	RelPath   string          // Path relative to the snapshot root
+	New       *restic.DirData // Entry in the newer snapshot, nil if added
+	Old       *restic.DirData // Entry in the older snapshot, nil if removed
+	Status    string
+	Diff      int64  // Signed size diff (new - old)
+	AbsDiff   uint64 // Absolute size diff
+	FilesDiff int64  // Signed diff of the number of files
+	DirsDiff  int64  // Signed diff of the number of directories
+	MovedFrom string // Path in the older snapshot, if moved
+	Stats     Stats  // Status of the entries under this node
+	Parent    *Node
+	Children  []*Node // Sorted by Diff, descending
+}
+
+// Stats counts the entries of a subtree by status
+type Stats struct {
+	Added     int
+	Removed   int
+	Moved     int
+	Changed   int
+	Modified  int
+	Unchanged int
+}
+
+func (s *Stats) add(other Stats) {
+	s.Added += other.Added
+	s.Removed += other.Removed
+	s.Moved += other.Moved
+	s.Changed += other.Changed
+	s.Modified += other.Modified
+	s.Unchanged += other.Unchanged
+}
+
+func (s *Stats) count(status string) {
+	switch status {
+	case StatusAdded:
+		s.Added++
+	case StatusRemoved:
+		s.Removed++
+	case StatusMoved:
+		s.Moved++
+	case StatusChanged:
+		s.Changed++
+	case StatusModified:
+		s.Modified++
+	default:
+		s.Unchanged++
+	}
+}
+
+func (s Stats) String() string {
+	str := fmt.Sprintf("%d added, %d removed", s.Added, s.Removed)
+	if s.Moved > 0 {
+		str += fmt.Sprintf(", %d moved", s.Moved)
+	}
+	str += fmt.Sprintf(", %d changed", s.Changed)
+	if s.Modified > 0 {
+		str += fmt.Sprintf(", %d modified", s.Modified)
+	}
+	return str + fmt.Sprintf(", %d unchanged", s.Unchanged)
+}
+
+// HasChanges reports if any entry is not unchanged
+func (s Stats) HasChanges() bool {
+	return s.Added+s.Removed+s.Moved+s.Changed+s.Modified > 0
+}
+
+// BuildTree pairs the entries of dirNew and dirOld by name, recursively.
+// Entries found in only one snapshot keep all their children, with the
+// other side nil.
+func BuildTree(dirNew, dirOld *restic.DirData) *Node {
+	root := buildNode(nil, "/", dirNew, dirOld)
+	root.RelPath = ""
+	return root
+}
+
+func buildNode(parent *Node, name string, dirNew, dirOld *restic.DirData) *Node {
+	n := &Node{
+		Name:   name,
+		New:    dirNew,
+		Old:    dirOld,
+		Parent: parent,
+	}
+	if parent != nil {
+		n.RelPath = path.Join(parent.RelPath, name)
+	}
+
+	var empty restic.DirData
+	entryNew, entryOld := dirNew, dirOld
+	switch {
+	case dirOld == nil:
+		entryOld = &empty
+	case dirNew == nil:
+		entryNew = &empty
+	}
+	n.Diff = entryNew.Size - entryOld.Size
+	n.AbsDiff = uint64(max(n.Diff, -n.Diff))
+	n.FilesDiff = entryNew.Files - entryOld.Files
+	n.DirsDiff = entryNew.Dirs - entryOld.Dirs
+
+	// Pair the children by name
+	var names []string
+	newChildren := make(map[string]*restic.DirData)
+	oldChildren := make(map[string]*restic.DirData)
+	if dirNew != nil {
+		for _, c := range dirNew.Children {
+			childName := filepath.Base(c.Path)
+			newChildren[childName] = c
+			names = append(names, childName)
+		}
+	}
+	if dirOld != nil {
+		for _, c := range dirOld.Children {
+			childName := filepath.Base(c.Path)
+			oldChildren[childName] = c
+			if _, ok := newChildren[childName]; !ok {
+				names = append(names, childName)
+			}
+		}
+	}
+
+	n.Children = make([]*Node, 0, len(names))
+	for _, childName := range names {
+		child := buildNode(n, childName, newChildren[childName], oldChildren[childName])
+		n.Children = append(n.Children, child)
+		n.Stats.count(child.Status)
+		n.Stats.add(child.Stats)
+	}
+	sort.SliceStable(n.Children, func(i, j int) bool {
+		return n.Children[i].Diff > n.Children[j].Diff
+	})
+	n.Status = n.status()
+
+	return n
+}
+
+// status compares the entries of n. The children must have their
+// status and stats set.
+func (n *Node) status() string {
+	switch {
+	case n.Status == StatusMoved:
+		return StatusMoved
+	case n.Old == nil:
+		return StatusAdded
+	case n.New == nil:
+		return StatusRemoved
+	case n.New.Size != n.Old.Size || n.New.Files != n.Old.Files || n.New.Dirs != n.Old.Dirs:
+		return StatusChanged
+	case entryModified(n.New, n.Old) || n.Stats.HasChanges():
+		return StatusModified
+	}
+	return StatusUnchanged
+}
+
+// entryModified compares the metadata of two entries with the same size.
+// Once both are hashed, the content replaces the modification time.
+// Directories change their time with any entry, so only the mode is used.
+func entryModified(dirNew, dirOld *restic.DirData) bool {
+	if dirNew.Mode != dirOld.Mode {
+		return true
+	}
+	if dirNew.IsDir {
+		return false
+	}
+	if dirNew.Hash != "" && dirOld.Hash != "" {
+		return dirNew.Hash != dirOld.Hash
+	}
+	return !dirNew.ModTime.Equal(dirOld.ModTime)
+}
+
+// DiffReadable returns the signed, human-readable diff
+func (n *Node) DiffReadable() string {
+	signStr := "+"
+	if n.Diff < 0 {
+		signStr = "-"
+	}
+	return fmt.Sprintf("%s%s", signStr, humanize.Bytes(n.AbsDiff))
+}
+
+// CountReadable returns the signed diff of files and directories
+func (n *Node) CountReadable() string {
+	if n.DirsDiff == 0 {
+		return fmt.Sprintf("%+d", n.FilesDiff)
+	}
+	return fmt.Sprintf("%+d / %+d", n.FilesDiff, n.DirsDiff)
+}
+
+// PathReadable returns the readable name of the entry in either snapshot
+func (n *Node) PathReadable() string {
+	if n.New != nil {
+		return n.New.PathReadable
+	}
+	return n.Old.PathReadable
+}
+
+// OldPathReadable returns the readable name of the entry in the older
+// snapshot, or its path if it was moved from another directory
+func (n *Node) OldPathReadable() string {
+	if n.Status == StatusMoved && path.Dir(n.MovedFrom) != path.Dir(n.RelPath) {
+		return "/" + n.MovedFrom
+	}
+	return n.Old.PathReadable
+}
+
+// Root returns the root of the tree n belongs to
+func (n *Node) Root() *Node {
+	root := n
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	return root
+}
+
+// Ancestors returns the nodes from the root to the parent of n
+func (n *Node) Ancestors() []*Node {
+	var ancestors []*Node
+	for p := n.Parent; p != nil; p = p.Parent {
+		ancestors = append([]*Node{p}, ancestors...)
+	}
+	return ancestors
+}
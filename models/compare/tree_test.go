@@ -0,0 +1,132 @@
+package compare
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gestic/restic"
+	"gestic/restic/restictest"
+)
+
+// loadTrees writes the files of two snapshots, by path relative to the
+// snapshot, and reads them back through a restic.DirSource
+func loadTrees(t *testing.T, newFiles, oldFiles map[string]string) (*restic.DirData, *restic.DirData) {
+	t.Helper()
+	root := t.TempDir()
+	restictest.WriteFiles(t, filepath.Join(root, "new"), newFiles)
+	restictest.WriteFiles(t, filepath.Join(root, "old"), oldFiles)
+
+	src := restic.DirSource{Root: root}
+	read := func(name string) *restic.DirData {
+		d, err := src.Tree(context.Background(), restic.Snapshot{Path: filepath.Join(root, name)}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	return read("new"), read("old")
+}
+
+// child returns the child of n with the given name
+func child(t *testing.T, n *Node, name string) *Node {
+	t.Helper()
+	for _, c := range n.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	t.Fatalf("%q has no child %q", n.RelPath, name)
+	return nil
+}
+
+func TestBuildTree(t *testing.T) {
+	newer, older := loadTrees(t, map[string]string{
+		"same.txt":       "same",
+		"grown.txt":      "grown!!",
+		"added/a.txt":    "aa",
+		"dir/inside.txt": "x",
+	}, map[string]string{
+		"same.txt":       "same",
+		"grown.txt":      "grown",
+		"removed.txt":    "removed",
+		"dir/inside.txt": "x",
+	})
+	tree := BuildTree(newer, older)
+
+	tests := []struct {
+		name   string
+		status string
+		diff   int64
+	}{
+		{"same.txt", StatusUnchanged, 0},
+		{"grown.txt", StatusChanged, 2},
+		{"added", StatusAdded, 2},
+		{"removed.txt", StatusRemoved, -7},
+		{"dir", StatusUnchanged, 0},
+	}
+	for _, tt := range tests {
+		n := child(t, tree, tt.name)
+		if n.Status != tt.status || n.Diff != tt.diff {
+			t.Errorf("%s: %s %+d, want %s %+d", tt.name, n.Status, n.Diff, tt.status, tt.diff)
+		}
+	}
+
+	a := child(t, child(t, tree, "added"), "a.txt")
+	if a.RelPath != "added/a.txt" || a.Old != nil || a.Parent.Parent != tree {
+		t.Errorf("added/a.txt = %+v, want a new entry under added", a)
+	}
+	want := Stats{Added: 2, Removed: 1, Changed: 1, Unchanged: 3}
+	if tree.Stats != want {
+		t.Errorf("stats = %v, want %v", tree.Stats, want)
+	}
+	for i := 1; i < len(tree.Children); i++ {
+		if tree.Children[i-1].Diff < tree.Children[i].Diff {
+			t.Errorf("children not sorted by diff: %s before %s", tree.Children[i-1].Name, tree.Children[i].Name)
+		}
+	}
+}
+
+func TestBuildTreeModified(t *testing.T) {
+	newer, older := loadTrees(t, map[string]string{"a": "new"}, map[string]string{"a": "old"})
+	a := newer.Children[0]
+	a.ModTime = a.ModTime.Add(time.Hour)
+
+	if n := child(t, BuildTree(newer, older), "a"); n.Status != StatusModified {
+		t.Errorf("status = %s, want %s", n.Status, StatusModified)
+	}
+}
+
+func TestCreateRecords(t *testing.T) {
+	newer, older := loadTrees(t, map[string]string{
+		"dir/sub/a": "aaa",
+		"b":         "b",
+	}, map[string]string{
+		"b": "bb",
+	})
+	tree := BuildTree(newer, older)
+
+	records := CreateRecords(tree, 0)
+	flat := FlattenRecords(records)
+	var paths []string
+	for _, r := range flat {
+		paths = append(paths, r.Path)
+	}
+	want := []string{"/dir", "/dir/sub", "/dir/sub/a", "/b"}
+	if len(paths) != len(want) {
+		t.Fatalf("paths = %v, want %v", paths, want)
+	}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Fatalf("paths = %v, want %v", paths, want)
+		}
+	}
+	if b := flat[3]; b.NewSize != 1 || b.OldSize != 2 || b.Diff != -1 || b.Status != StatusChanged {
+		t.Errorf("b = %+v, want a changed entry from 2 to 1 bytes", b)
+	}
+
+	if records := CreateRecords(tree, 1); len(records[0].Children) != 0 {
+		t.Errorf("depth 1 kept the children of %s", records[0].Path)
+	}
+}
@@ -0,0 +1,38 @@
+package growth
+
+import "github.com/charmbracelet/bubbles/key"
+
+type keymap struct {
+	Back key.Binding
+	Quit key.Binding
+	Help key.Binding
+}
+
+func (k keymap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Back, k.Quit}
+}
+
+func (k keymap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Back, k.Help},
+		{k.Quit},
+	}
+}
+
+// DefaultKeyMap returns the keys of the growth report
+func DefaultKeyMap() keymap {
+	return keymap{
+		Help: key.NewBinding(
+			key.WithKeys("?"),
+			key.WithHelp("?", "toggle help"),
+		),
+		Quit: key.NewBinding(
+			key.WithKeys("ctrl+c", "q"),
+			key.WithHelp("ctrl+c", "quit"),
+		),
+		Back: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "Back"),
+		),
+	}
+}
@@ -0,0 +1,227 @@
+package growth
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gestic/models"
+	"gestic/models/compare"
+	"gestic/restic"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// DefaultSince is the range of the report opened from the selector
+const DefaultSince = 30 * 24 * time.Hour
+
+// ViewportHeight is the number of rows of the table
+const ViewportHeight = 12
+
+// jumpLines is the number of jumps listed for the selected path
+const jumpLines = 5
+
+// Model ranks the paths that grew the most over a range of snapshots
+type Model struct {
+	prevModel tea.Model
+	help      help.Model
+	keyMap    keymap
+	width     int
+	height    int
+
+	source    restic.SnapshotSource
+	snapshots []restic.Snapshot
+	moves     compare.MoveScope
+
+	updates models.Updates
+	cancel  context.CancelFunc
+	done    int // Snapshots read
+	loaded  bool
+	report  Report
+	err     error
+	table   table.Model
+}
+
+type progressMsg struct {
+	done  int
+	total int
+}
+
+type reportMsg struct {
+	report Report
+	err    error
+}
+
+// InitialModel returns the growth report of snapshots, oldest first
+func InitialModel(prevModel tea.Model, width, height int, source restic.SnapshotSource, snapshots []restic.Snapshot, moves compare.MoveScope) *Model {
+	m := &Model{
+		prevModel: prevModel,
+		help:      help.New(),
+		keyMap:    DefaultKeyMap(),
+		width:     width,
+		height:    height,
+		source:    source,
+		snapshots: snapshots,
+		moves:     moves,
+		table: table.New(
+			table.WithFocused(true),
+			table.WithHeight(ViewportHeight),
+			table.WithStyles(tableStyles),
+		),
+	}
+	m.resizeTable()
+	return m
+}
+
+func (m *Model) Init() tea.Cmd {
+	return tea.Batch(tea.ClearScreen, m.startCmd())
+}
+
+// startCmd computes the report in the background. The progress is
+// sent through m.updates.
+func (m *Model) startCmd() tea.Cmd {
+	source, snapshots, moves := m.source, m.snapshots, m.moves
+	updates := models.NewUpdates()
+	m.updates = updates
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+
+	go func() {
+		report, err := Compute(ctx, source, snapshots, DefaultDepth, moves, func(done, total int) {
+			updates.Progress(progressMsg{done: done, total: total})
+		})
+		updates.Done(ctx, reportMsg{report: report, err: err})
+	}()
+	return updates.Listen()
+}
+
+func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.resizeTable()
+		return m, nil
+
+	case progressMsg:
+		m.done = msg.done
+		return m, m.updates.Listen()
+
+	case reportMsg:
+		m.loaded = true
+		m.report = msg.report
+		m.err = msg.err
+		m.updateTable()
+		return m, nil
+
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keyMap.Quit):
+			return m, tea.Quit
+
+		case key.Matches(msg, m.keyMap.Help):
+			m.help.ShowAll = !m.help.ShowAll
+			return m, nil
+
+		case key.Matches(msg, m.keyMap.Back):
+			if m.prevModel == nil {
+				return m, nil
+			}
+			// Stop reading the snapshots if the report isn't done
+			m.cancel()
+			return m.prevModel, func() tea.Msg {
+				return tea.WindowSizeMsg{Width: m.width, Height: m.height}
+			}
+		}
+	}
+
+	var cmd tea.Cmd
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+func (m *Model) View() string {
+	var output strings.Builder
+
+	header := "Growth"
+	if len(m.snapshots) > 0 {
+		first, last := m.snapshots[0], m.snapshots[len(m.snapshots)-1]
+		header = fmt.Sprintf("Growth: %d snapshots from %s to %s", len(m.snapshots),
+			first.Date.Format("2006-01-02 15:04"), last.Date.Format("2006-01-02 15:04"))
+	}
+	output.WriteString(headerStyle.Render(header))
+	output.WriteString("\n")
+
+	switch {
+	case m.err != nil:
+		output.WriteString(fmt.Sprintf("Can't compute the growth: %v\n", m.err))
+	case !m.loaded:
+		output.WriteString(fmt.Sprintf("Reading snapshots... %d/%d\n", m.done, len(m.snapshots)))
+	case len(m.report.Entries) == 0:
+		output.WriteString("No path changed in the range.\n")
+	default:
+		output.WriteString(m.table.View())
+		output.WriteString("\n")
+		output.WriteString(m.jumpsView())
+	}
+
+	output.WriteString("\n")
+	output.WriteString(m.help.View(m.keyMap))
+	return output.String()
+}
+
+// jumpsView lists the largest jumps of the selected path
+func (m *Model) jumpsView() string {
+	cursor := m.table.Cursor()
+	if cursor < 0 || cursor >= len(m.report.Entries) {
+		return ""
+	}
+	e := m.report.Entries[cursor]
+
+	jumps := make([]Jump, len(e.Jumps))
+	copy(jumps, e.Jumps)
+	sort.SliceStable(jumps, func(i, j int) bool {
+		return jumps[i].Diff > jumps[j].Diff
+	})
+
+	var output strings.Builder
+	fmt.Fprintf(&output, "%s changed in %d of %d pairs:\n", m.report.Name(e), len(e.Jumps), len(m.report.Pairs))
+	for _, j := range jumps[:min(len(jumps), jumpLines)] {
+		fmt.Fprintf(&output, "%10s  %s\n", SignedBytes(j.Diff), j.Pair)
+	}
+	return output.String()
+}
+
+// resizeTable gives the path column the width left by the other ones
+func (m *Model) resizeTable() {
+	pairWidth := 46
+	pathWidth := max(m.width-10-12-pairWidth-8-2, 20)
+	columns := []table.Column{
+		{Title: "Growth", Width: 10},
+		{Title: "Largest jump", Width: 12},
+		{Title: "Between", Width: pairWidth},
+		{Title: "Path", Width: pathWidth},
+	}
+	m.table.SetColumns(columns)
+}
+
+func (m *Model) updateTable() {
+	rows := make([]table.Row, 0, len(m.report.Entries))
+	for _, e := range m.report.Entries {
+		largest := e.Largest()
+		rows = append(rows, table.Row{
+			SignedBytes(e.Growth),
+			SignedBytes(largest.Diff),
+			largest.Pair.String(),
+			m.report.Name(e),
+		})
+	}
+	m.table.SetRows(rows)
+	m.table.GotoTop()
+}
@@ -0,0 +1,171 @@
+package growth
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"gestic/models/compare"
+	"gestic/restic"
+
+	"github.com/dustin/go-humanize"
+)
+
+// DefaultDepth is the depth of the ranked paths
+const DefaultDepth = 3
+
+// Pair is two snapshots of a range compared with each other
+type Pair struct {
+	Older restic.Snapshot
+	Newer restic.Snapshot
+}
+
+func (p Pair) String() string {
+	return fmt.Sprintf("%s → %s (%s → %s)",
+		p.Older.ShortId, p.Newer.ShortId,
+		p.Older.Date.Format("2006-01-02"), p.Newer.Date.Format("2006-01-02"))
+}
+
+// Jump is the growth of a path between the snapshots of a pair
+type Jump struct {
+	Pair Pair
+	Diff int64
+}
+
+// Entry is a path and its growth over a range of snapshots
+type Entry struct {
+	Group  string // Host and paths of the snapshots of the path
+	Path   string
+	Growth int64  // Sum of the diffs of the pairs
+	Jumps  []Jump // Pairs where the size changed, oldest first
+}
+
+// Largest returns the jump with the largest growth
+func (e *Entry) Largest() Jump {
+	var largest Jump
+	for i, j := range e.Jumps {
+		if i == 0 || j.Diff > largest.Diff {
+			largest = j
+		}
+	}
+	return largest
+}
+
+// Report ranks the paths of a range of snapshots by growth
+type Report struct {
+	Snapshots []restic.Snapshot // Oldest first
+	Pairs     []Pair
+	Entries   []*Entry // Largest growth first
+	Groups    int      // Sets of snapshots compared with each other
+}
+
+// Name returns the path of e, after its group if the report has several
+func (r *Report) Name(e *Entry) string {
+	if r.Groups > 1 {
+		return e.Group + " " + e.Path
+	}
+	return e.Path
+}
+
+// groupName returns the host and paths of s
+func groupName(s restic.Snapshot) string {
+	return s.Hostname + ":" + strings.Join(slices.Sorted(slices.Values(s.Paths)), ",")
+}
+
+// SignedBytes returns the human-readable size with its sign
+func SignedBytes(size int64) string {
+	sign := "+"
+	if size < 0 {
+		sign = "-"
+	}
+	return sign + humanize.Bytes(uint64(max(size, -size)))
+}
+
+// Range returns the snapshots taken from since, oldest first. Only the
+// ones of host are returned, unless it's empty.
+func Range(snapshots []restic.Snapshot, since time.Time, host string) []restic.Snapshot {
+	var found []restic.Snapshot
+	for _, s := range snapshots {
+		if !s.Date.Before(since) && (host == "" || s.Hostname == host) {
+			found = append(found, s)
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].Date.Before(found[j].Date)
+	})
+	return found
+}
+
+// Compute compares each snapshot with the previous one of the same
+// host and paths, see restic.CheckComparable, and adds the diffs of the
+// paths up to depth. Only the last tree of each host and paths is kept
+// in memory. progress is called after each snapshot is read. It stops
+// when ctx is cancelled.
+func Compute(ctx context.Context, source restic.SnapshotSource, snapshots []restic.Snapshot, depth int, moves compare.MoveScope, progress func(done, total int)) (Report, error) {
+	if len(snapshots) < 2 {
+		return Report{}, fmt.Errorf("the range has %d snapshots, at least 2 are needed", len(snapshots))
+	}
+	report := Report{Snapshots: snapshots}
+	entries := make(map[[2]string]*Entry)
+
+	// The last snapshot of each group and its tree
+	var lastSnapshots []restic.Snapshot
+	var lastTrees []*restic.DirData
+	for i, s := range snapshots {
+		newer, err := source.Tree(ctx, s, nil)
+		if err != nil {
+			return Report{}, fmt.Errorf("can't read snapshot %s: %w", s.ShortId, err)
+		}
+		group := slices.IndexFunc(lastSnapshots, func(last restic.Snapshot) bool {
+			return restic.CheckComparable(last, s) == nil
+		})
+		if group < 0 {
+			lastSnapshots = append(lastSnapshots, s)
+			lastTrees = append(lastTrees, newer)
+			if progress != nil {
+				progress(i+1, len(snapshots))
+			}
+			continue
+		}
+
+		pair := Pair{Older: lastSnapshots[group], Newer: s}
+		report.Pairs = append(report.Pairs, pair)
+		tree := compare.BuildTree(newer, lastTrees[group])
+		compare.DetectMoves(tree, moves)
+		for _, r := range compare.FlattenRecords(compare.CreateRecords(tree, depth)) {
+			if r.Diff == 0 {
+				continue
+			}
+			key := [2]string{groupName(s), r.Path}
+			e, ok := entries[key]
+			if !ok {
+				e = &Entry{Group: groupName(s), Path: r.Path}
+				entries[key] = e
+				report.Entries = append(report.Entries, e)
+			}
+			e.Growth += r.Diff
+			e.Jumps = append(e.Jumps, Jump{Pair: pair, Diff: r.Diff})
+		}
+
+		lastSnapshots[group], lastTrees[group] = s, newer
+		if progress != nil {
+			progress(i+1, len(snapshots))
+		}
+	}
+	if len(report.Pairs) == 0 {
+		return Report{}, fmt.Errorf("no two snapshots of the range have the same host and paths")
+	}
+	groups := make(map[string]bool)
+	for _, p := range report.Pairs {
+		groups[groupName(p.Newer)] = true
+	}
+	report.Groups = len(groups)
+
+	sort.SliceStable(report.Entries, func(i, j int) bool {
+		return report.Entries[i].Growth > report.Entries[j].Growth
+	})
+	return report, nil
+}
@@ -0,0 +1,141 @@
+package growth
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gestic/models/compare"
+	"gestic/restic"
+	"gestic/restic/restictest"
+)
+
+func TestRange(t *testing.T) {
+	day := func(n int) time.Time { return time.Date(2024, 1, n, 0, 0, 0, 0, time.UTC) }
+	snapshots := []restic.Snapshot{
+		{Id: "c", Date: day(3), Hostname: "h1"},
+		{Id: "a", Date: day(1), Hostname: "h1"},
+		{Id: "b", Date: day(2), Hostname: "h2"},
+	}
+	tests := []struct {
+		since time.Time
+		host  string
+		want  string
+	}{
+		{day(1), "", "abc"},
+		{day(2), "", "bc"},
+		{day(1), "h1", "ac"},
+		{day(4), "", ""},
+	}
+	for _, tt := range tests {
+		var got string
+		for _, s := range Range(snapshots, tt.since, tt.host) {
+			got += s.Id
+		}
+		if got != tt.want {
+			t.Errorf("Range(%s, %q) = %s, want %s", tt.since.Format("2006-01-02"), tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestCompute(t *testing.T) {
+	root := t.TempDir()
+	restictest.WriteFiles(t, root, map[string]string{
+		"1/data/a": "a",
+		"1/logs/l": "l",
+		"2/data/a": "a",
+		"2/logs/l": "l",
+		"3/data/a": "a++",
+		"3/logs/l": "l++++",
+		"4/data/a": "a+++++++",
+		"4/logs/l": "l++++",
+	})
+	day := func(n int) time.Time { return time.Date(2024, 1, n, 0, 0, 0, 0, time.UTC) }
+	snapshot := func(name, host string) restic.Snapshot {
+		n := int(name[0] - '0')
+		return restic.Snapshot{Id: name, ShortId: name, Date: day(n), Hostname: host, Paths: []string{"/srv"}, Path: filepath.Join(root, name)}
+	}
+
+	tests := []struct {
+		name      string
+		snapshots []restic.Snapshot
+		pairs     []string         // Older and newer IDs
+		growth    map[string]int64 // By name in the report
+		err       bool
+	}{
+		{
+			name:      "one host",
+			snapshots: []restic.Snapshot{snapshot("1", "h1"), snapshot("2", "h1"), snapshot("3", "h1"), snapshot("4", "h1")},
+			pairs:     []string{"12", "23", "34"},
+			growth:    map[string]int64{"/data": 7, "/logs": 4},
+		},
+		{
+			name:      "two hosts",
+			snapshots: []restic.Snapshot{snapshot("1", "h1"), snapshot("2", "h2"), snapshot("3", "h1"), snapshot("4", "h2")},
+			pairs:     []string{"13", "24"},
+			growth:    map[string]int64{"h1:/srv /data": 2, "h1:/srv /logs": 4, "h2:/srv /data": 7, "h2:/srv /logs": 4},
+		},
+		{
+			name:      "no pair",
+			snapshots: []restic.Snapshot{snapshot("1", "h1"), snapshot("2", "h2")},
+			err:       true,
+		},
+		{
+			name:      "one snapshot",
+			snapshots: []restic.Snapshot{snapshot("1", "h1")},
+			err:       true,
+		},
+	}
+	for _, tt := range tests {
+		var done int
+		report, err := Compute(context.Background(), restic.DirSource{Root: root}, tt.snapshots, 1, compare.MovesOff, func(d, total int) { done = d })
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: no error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		var pairs []string
+		for _, p := range report.Pairs {
+			pairs = append(pairs, p.Older.Id+p.Newer.Id)
+		}
+		if len(pairs) != len(tt.pairs) {
+			t.Errorf("%s: pairs = %v, want %v", tt.name, pairs, tt.pairs)
+		}
+		for i := range min(len(pairs), len(tt.pairs)) {
+			if pairs[i] != tt.pairs[i] {
+				t.Errorf("%s: pairs = %v, want %v", tt.name, pairs, tt.pairs)
+				break
+			}
+		}
+
+		growth := make(map[string]int64)
+		for _, e := range report.Entries {
+			growth[report.Name(e)] = e.Growth
+		}
+		if len(growth) != len(tt.growth) {
+			t.Errorf("%s: growth = %v, want %v", tt.name, growth, tt.growth)
+		}
+		for name, g := range tt.growth {
+			if growth[name] != g {
+				t.Errorf("%s: growth = %v, want %v", tt.name, growth, tt.growth)
+				break
+			}
+		}
+		if done != len(tt.snapshots) {
+			t.Errorf("%s: progress = %d, want %d", tt.name, done, len(tt.snapshots))
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Compute(ctx, restic.DirSource{Root: root}, []restic.Snapshot{snapshot("1", "h1"), snapshot("2", "h1")}, 1, compare.MovesOff, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"context"
+
+	"github.com/charmbracelet/bubbletea"
+)
+
+// BackgroundMsg is the result of the background work of a screen. It
+// is delivered to that screen even when another one is shown on top.
+type BackgroundMsg interface {
+	Background()
+}
+
+// Forward passes msg to prev, the screen below the shown one, if msg
+// is the result of its background work
+func Forward(prev tea.Model, msg tea.Msg) tea.Cmd {
+	if _, ok := msg.(BackgroundMsg); !ok || prev == nil {
+		return nil
+	}
+	_, cmd := prev.Update(msg)
+	return cmd
+}
+
+// Updates carries the progress of background work to the UI
+type Updates chan tea.Msg
+
+func NewUpdates() Updates {
+	return make(Updates, 1)
+}
+
+// Progress sends msg unless the UI hasn't read the previous update yet,
+// as a newer one will follow
+func (u Updates) Progress(msg tea.Msg) {
+	select {
+	case u <- msg:
+	default:
+	}
+}
+
+// Done sends the last message of the work, unless ctx was cancelled
+// and nobody listens anymore
+func (u Updates) Done(ctx context.Context, msg tea.Msg) {
+	select {
+	case u <- msg:
+	case <-ctx.Done():
+	}
+}
+
+// Listen waits for the next update
+func (u Updates) Listen() tea.Cmd {
+	return func() tea.Msg {
+		return <-u
+	}
+}
@@ -3,22 +3,34 @@ package selector
 import "github.com/charmbracelet/bubbles/key"
 
 type keymap struct {
-	Quit   key.Binding
-	Help   key.Binding
-	Select key.Binding
-	Clear  key.Binding
-	Accept key.Binding
+	Quit     key.Binding
+	Help     key.Binding
+	Select   key.Binding
+	Timeline key.Binding
+	Growth   key.Binding
+	Query    key.Binding
+
+	LatestPrevious key.Binding
+	LatestWeek     key.Binding
+	HostPrevious   key.Binding
+	GroupByHost    key.Binding
+	Clear          key.Binding
+	Accept         key.Binding
+	Cancel         key.Binding
 }
 
 func (k keymap) ShortHelp() []key.Binding {
-	return []key.Binding{k.Select, k.Clear, k.Accept}
+	return []key.Binding{k.Select, k.Timeline, k.Clear, k.Accept}
 }
 
 func (k keymap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.Select, k.Help}, // first column
 		{k.Clear, k.Quit},  // second column
-		{k.Accept},
+		{k.Timeline, k.Accept},
+		{k.Growth, k.Query},
+		{k.GroupByHost, k.Cancel},
+		{k.LatestPrevious, k.LatestWeek, k.HostPrevious},
 	}
 }
 
@@ -37,6 +49,34 @@ func DefaultKeyMap() keymap {
 			key.WithKeys(" "),
 			key.WithHelp("<space>", "Select"),
 		),
+		Timeline: key.NewBinding(
+			key.WithKeys("t"),
+			key.WithHelp("t", "Add to timeline"),
+		),
+		Growth: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "Growth report"),
+		),
+		Query: key.NewBinding(
+			key.WithKeys("/"),
+			key.WithHelp("/", "Filter by host, tag or path"),
+		),
+		GroupByHost: key.NewBinding(
+			key.WithKeys("H"),
+			key.WithHelp("H", "Group by host"),
+		),
+		LatestPrevious: key.NewBinding(
+			key.WithKeys("p"),
+			key.WithHelp("p", "Latest vs previous"),
+		),
+		LatestWeek: key.NewBinding(
+			key.WithKeys("w"),
+			key.WithHelp("w", "Latest vs a week ago"),
+		),
+		HostPrevious: key.NewBinding(
+			key.WithKeys("P"),
+			key.WithHelp("P", "Selected vs host's previous"),
+		),
 		Clear: key.NewBinding(
 			key.WithKeys("backspace"),
 			key.WithHelp("<backspace>", "Clear"),
@@ -45,5 +85,9 @@ func DefaultKeyMap() keymap {
 			key.WithKeys("enter"),
 			key.WithHelp("<enter>", "Open repositories"),
 		),
+		Cancel: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("<esc>", "Cancel loading"),
+		),
 	}
 }
@@ -0,0 +1,185 @@
+package selector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gestic/models"
+	"gestic/models/compare"
+	"gestic/models/timeline"
+	"gestic/restic"
+
+	"github.com/charmbracelet/bubbletea"
+	"github.com/dustin/go-humanize"
+)
+
+// loadMsg is an update of the load that writes to updates. Updates of
+// a cancelled load are ignored.
+type loadMsg struct {
+	updates models.Updates
+	msg     tea.Msg
+}
+
+// loadProgressMsg reports the progress of the snapshot index of the load
+type loadProgressMsg struct {
+	index    int
+	progress restic.TreeProgress
+}
+
+type loadErrorMsg struct {
+	err error
+}
+
+// listenCmd waits for the next update of a load
+func listenCmd(updates models.Updates) tea.Cmd {
+	return func() tea.Msg {
+		return loadMsg{updates: updates, msg: <-updates}
+	}
+}
+
+// GetEntriesAsync reads the tree of s, sending it to c or the error to e
+func GetEntriesAsync(ctx context.Context, source restic.SnapshotSource, s restic.Snapshot, progress func(restic.TreeProgress), c chan []*restic.DirData, e chan error) {
+	rootNode, err := source.Tree(ctx, s, progress)
+	if err != nil {
+		e <- fmt.Errorf("can't read snapshot %s: %w", s.ShortId, err)
+		return
+	}
+
+	c <- []*restic.DirData{rootNode}
+}
+
+// loadTrees reads the trees of snapshots concurrently. The progress of
+// each one is sent to updates. The first error stops the other ones.
+func loadTrees(ctx context.Context, source restic.SnapshotSource, snapshots []restic.Snapshot, updates models.Updates) ([]*restic.DirData, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	roots := make([]*restic.DirData, len(snapshots))
+	entriesChans := make([]chan []*restic.DirData, len(snapshots))
+	// Shared, so that an error is seen while waiting for another snapshot
+	errChan := make(chan error, len(snapshots))
+	for i, s := range snapshots {
+		entriesChans[i] = make(chan []*restic.DirData, 1)
+		progress := func(p restic.TreeProgress) {
+			updates.Progress(loadProgressMsg{index: i, progress: p})
+		}
+		go GetEntriesAsync(ctx, source, s, progress, entriesChans[i], errChan)
+	}
+
+	for i := range snapshots {
+		select {
+		case entries := <-entriesChans[i]:
+			roots[i] = entries[0]
+		case err := <-errChan:
+			return nil, err
+		}
+	}
+	return roots, nil
+}
+
+// startLoad reads the trees of snapshots in the background, then sends
+// the message built by done. Cancel stops it.
+func (m *Model) startLoad(snapshots []restic.Snapshot, done func(roots []*restic.DirData) tea.Msg) tea.Cmd {
+	source := m.source
+	updates := models.NewUpdates()
+	ctx, cancel := context.WithCancel(context.Background())
+	m.updates, m.cancel = updates, cancel
+	m.loading = snapshots
+	m.progress = make([]restic.TreeProgress, len(snapshots))
+	m.waiting = true
+	m.warning, m.err = "", nil
+
+	go func() {
+		var msg tea.Msg
+		roots, err := loadTrees(ctx, source, snapshots, updates)
+		if err != nil {
+			msg = loadErrorMsg{err: err}
+		} else {
+			msg = done(roots)
+		}
+		updates.Done(ctx, msg)
+	}()
+	return tea.Batch(m.spinner.Tick, listenCmd(updates))
+}
+
+// stopLoad cancels the current load, if any
+func (m *Model) stopLoad() {
+	if m.cancel != nil {
+		m.cancel()
+	}
+	m.updates, m.cancel = nil, nil
+	m.waiting = false
+}
+
+// updateLoad handles an update of the current load
+func (m Model) updateLoad(msg loadMsg) (tea.Model, tea.Cmd) {
+	if msg.updates != m.updates {
+		return m, nil
+	}
+	switch msg := msg.msg.(type) {
+	case loadProgressMsg:
+		m.progress[msg.index] = msg.progress
+		return m, listenCmd(m.updates)
+	case loadErrorMsg:
+		m.stopLoad()
+		// Cancelled by the user, who already knows
+		if !errors.Is(msg.err, context.Canceled) {
+			m.err = msg.err
+		}
+		return m, nil
+	default:
+		m.stopLoad()
+		return m.Update(msg)
+	}
+}
+
+// LoadSnapshots reads the trees of the selected snapshots
+func (m *Model) LoadSnapshots() tea.Cmd {
+	moves := m.options.Moves
+	snapshots := []restic.Snapshot{m.snapshots[m.snapshotNew], m.snapshots[m.snapshotOld]}
+	return m.startLoad(snapshots, func(roots []*restic.DirData) tea.Msg {
+		tree := compare.BuildTree(roots[0], roots[1])
+		compare.DetectMoves(tree, moves)
+		return SnapshotSelectionMsg{
+			Newer: roots[0],
+			Older: roots[1],
+			Tree:  tree,
+		}
+	})
+}
+
+// LoadTimeline reads the trees of the timeline snapshots
+func (m *Model) LoadTimeline() tea.Cmd {
+	var indexes []int
+	for i := range m.timeline {
+		indexes = append(indexes, i)
+	}
+	// The snapshots are listed from the oldest
+	sort.Ints(indexes)
+
+	snapshots := make([]restic.Snapshot, len(indexes))
+	for i, index := range indexes {
+		snapshots[i] = m.snapshots[index]
+	}
+	return m.startLoad(snapshots, func(roots []*restic.DirData) tea.Msg {
+		return TimelineSelectionMsg{
+			Snapshots: snapshots,
+			Tree:      timeline.BuildTree(roots),
+		}
+	})
+}
+
+// progressView shows what was read of each snapshot of the load
+func (m Model) progressView() string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("%s Loading snapshots, esc to cancel\n", m.spinner.View()))
+	for i, s := range m.loading {
+		p := m.progress[i]
+		output.WriteString(fmt.Sprintf("  %s: %s dirs, %s files, %s\n", s.ShortId,
+			humanize.Comma(p.Dirs), humanize.Comma(p.Files), humanize.Bytes(uint64(p.Bytes))))
+	}
+	return output.String()
+}
@@ -0,0 +1,106 @@
+package selector
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gestic/models"
+	"gestic/models/compare"
+	"gestic/restic"
+
+	"github.com/charmbracelet/bubbletea"
+)
+
+// treeSource reads the trees of the snapshots by ID. A snapshot
+// without a tree blocks until the read is cancelled.
+type treeSource struct {
+	restic.DirSource
+	trees map[string]*restic.DirData
+	errs  map[string]error
+}
+
+func (src treeSource) Tree(ctx context.Context, s restic.Snapshot, progress func(restic.TreeProgress)) (*restic.DirData, error) {
+	if err, ok := src.errs[s.Id]; ok {
+		return nil, err
+	}
+	if d, ok := src.trees[s.Id]; ok {
+		progress(restic.TreeProgress{Files: 1})
+		return d, nil
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestLoadTrees(t *testing.T) {
+	a, b := &restic.DirData{Path: "a"}, &restic.DirData{Path: "b"}
+	failed := errors.New("restic ls failed")
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		source  treeSource
+		want    []*restic.DirData
+		wantErr error
+	}{
+		{"loaded", context.Background(), treeSource{trees: map[string]*restic.DirData{"a": a, "b": b}}, []*restic.DirData{a, b}, nil},
+		{"failed", context.Background(), treeSource{trees: map[string]*restic.DirData{"a": a}, errs: map[string]error{"b": failed}}, nil, failed},
+		{"other one blocked", context.Background(), treeSource{errs: map[string]error{"b": failed}}, nil, failed},
+		{"cancelled", cancelled, treeSource{}, nil, context.Canceled},
+	}
+	for _, tt := range tests {
+		snapshots := []restic.Snapshot{{Id: "a", ShortId: "a"}, {Id: "b", ShortId: "b"}}
+		done := make(chan struct{})
+		var roots []*restic.DirData
+		var err error
+		go func() {
+			roots, err = loadTrees(tt.ctx, tt.source, snapshots, models.NewUpdates())
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: the load didn't stop", tt.name)
+		}
+		if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if len(roots) != len(tt.want) || (len(roots) == 2 && (roots[0] != a || roots[1] != b)) {
+			t.Errorf("%s: roots = %v, want %v", tt.name, roots, tt.want)
+		}
+	}
+}
+
+func TestUpdateLoad(t *testing.T) {
+	snapshots := []restic.Snapshot{{Id: "a", ShortId: "a"}, {Id: "b", ShortId: "b"}}
+	m := InitialModel(treeSource{}, snapshots, compare.Options{})
+	m.startLoad(snapshots, func(roots []*restic.DirData) tea.Msg { return nil })
+	defer m.stopLoad()
+	failed := errors.New("restic ls failed")
+
+	tests := []struct {
+		name        string
+		msg         loadMsg
+		err         error
+		waiting     bool
+		progressDir int64
+	}{
+		{"progress", loadMsg{updates: m.updates, msg: loadProgressMsg{index: 1, progress: restic.TreeProgress{Dirs: 3}}}, nil, true, 3},
+		{"previous load", loadMsg{updates: models.NewUpdates(), msg: loadErrorMsg{err: failed}}, nil, true, 0},
+		{"cancelled", loadMsg{updates: m.updates, msg: loadErrorMsg{err: context.Canceled}}, nil, false, 0},
+		{"failed", loadMsg{updates: m.updates, msg: loadErrorMsg{err: failed}}, failed, false, 0},
+	}
+	for _, tt := range tests {
+		current := m
+		current.progress = make([]restic.TreeProgress, len(snapshots))
+		next, _ := current.updateLoad(tt.msg)
+		got := next.(Model)
+		if got.err != tt.err || got.waiting != tt.waiting || got.progress[1].Dirs != tt.progressDir {
+			t.Errorf("%s: err = %v, waiting = %v, dirs = %d, want %v, %v, %d", tt.name,
+				got.err, got.waiting, got.progress[1].Dirs, tt.err, tt.waiting, tt.progressDir)
+		}
+	}
+}
@@ -1,20 +1,29 @@
 package selector
 
 import (
+	"context"
 	"fmt"
+	"gestic/models"
 	"gestic/models/compare"
+	"gestic/models/growth"
+	"gestic/models/timeline"
 	"gestic/restic"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
 
 	"github.com/charmbracelet/bubbletea"
 )
 
 type Model struct {
+	source      restic.SnapshotSource
+	options     compare.Options
 	help        help.Model
 	keyMap      keymap
 	width       int
@@ -22,43 +31,78 @@ type Model struct {
 	snapshots   []restic.Snapshot
 	snapshotNew int
 	snapshotOld int
+	timeline    map[int]bool // Snapshots of the timeline
 	table       table.Model
 	spinner     spinner.Model
 	waiting     bool
+	start       tea.Cmd // Run by Init, see Preselect
+
+	updates  models.Updates // Updates of the current load
+	cancel   context.CancelFunc
+	loading  []restic.Snapshot
+	progress []restic.TreeProgress // Of each loading snapshot
+	err      error                 // Why the last load failed
+
+	queryInput  textinput.Model
+	query       string // Filter of the snapshots, see parseQuery
+	groupByHost bool
+	warning     string // Why the selected snapshots shouldn't be compared
+	forced      bool   // Compare them anyway
 }
 
-func InitialModel(s []restic.Snapshot) Model {
+func InitialModel(source restic.SnapshotSource, s []restic.Snapshot, options compare.Options) Model {
 	columns := []table.Column{
 		{Title: " ", Width: 1},
-		{Title: "ID", Width: 12},
-		{Title: "DATE", Width: 22},
-		{Title: "SIZE", Width: 12},
+		{Title: "ID", Width: 10},
+		{Title: "DATE", Width: 19},
+		{Title: "HOST", Width: 14},
+		{Title: "TAGS", Width: 14},
+		{Title: "PATHS", Width: 28},
+		{Title: "SIZE", Width: 10},
 	}
+	queryInput := textinput.New()
+	queryInput.Prompt = "Query: "
+	queryInput.Placeholder = "host:NAME tag:NAME path:PATH"
 	spin := spinner.New()
 	spin.Spinner = spinner.Line
 	m := Model{
+		source:      source,
+		options:     options,
 		help:        help.New(),
 		keyMap:      DefaultKeyMap(),
 		snapshots:   s,
 		snapshotNew: -1,
 		snapshotOld: -1,
+		timeline:    make(map[int]bool),
 		table: table.New(
 			table.WithColumns(columns),
 			table.WithFocused(true),
 			table.WithHeight(10),
 			table.WithStyles(tableStyles),
 		),
-		spinner: spin,
-		waiting: false,
+		spinner:    spin,
+		waiting:    false,
+		queryInput: queryInput,
 	}
 	m.table.SetRows(m.UpdateRows())
 	m.table.GotoBottom()
 	return m
 }
 
+// Preselect returns the model comparing newer and older as soon as it
+// starts, without waiting for a selection
+func (m Model) Preselect(newer, older restic.Snapshot) Model {
+	m.snapshotNew, m.snapshotOld = m.indexOf(newer), m.indexOf(older)
+	m.orderSelection()
+	m.table.SetRows(m.UpdateRows())
+	m.start = m.LoadSnapshots()
+	return m
+}
+
 func (m Model) Init() tea.Cmd {
 	return tea.Batch(
 		tea.ClearScreen,
+		m.start,
 	)
 }
 
@@ -72,17 +116,46 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 	case SnapshotSelectionMsg:
 		metadata := restic.SnapshotsMetadata{
-			NewerFullPath: m.snapshots[m.snapshotNew].Path,
-			NewerId:       m.snapshots[m.snapshotNew].Id,
-			OlderFullPath: m.snapshots[m.snapshotOld].Path,
-			OlderId:       m.snapshots[m.snapshotOld].Id,
+			NewerFullPath: msg.Newer.Path,
+			NewerId:       m.snapshots[m.snapshotNew].ShortId,
+			OlderFullPath: msg.Older.Path,
+			OlderId:       m.snapshots[m.snapshotOld].ShortId,
+			Source:        m.source,
+			Newer:         m.snapshots[m.snapshotNew],
+			Older:         m.snapshots[m.snapshotOld],
 		}
-		compareModel := compare.InitialModel(nil, m.width, m.height, msg.Newer, msg.Older, metadata)
+		compareModel := compare.InitialModel(nil, m.width, m.height, msg.Tree, metadata, m.options)
 		return compareModel, tea.Batch(
 			compareModel.Init(),
 		)
 
+	case loadMsg:
+		return m.updateLoad(msg)
+
+	case TimelineSelectionMsg:
+		timelineModel := timeline.InitialModel(nil, m.width, m.height, msg.Tree, msg.Snapshots, timeline.SortByGrowth)
+		return timelineModel, timelineModel.Init()
+
 	case tea.KeyMsg:
+		// The query bar gets all the keys
+		if m.queryInput.Focused() {
+			return m.updateQuery(msg)
+		}
+
+		// Only the load can be cancelled until it is done
+		if m.waiting {
+			switch {
+			case key.Matches(msg, m.keyMap.Quit):
+				return m, tea.Quit
+			case key.Matches(msg, m.keyMap.Help):
+				m.help.ShowAll = !m.help.ShowAll
+			case key.Matches(msg, m.keyMap.Cancel):
+				m.stopLoad()
+				m.warning = "Loading cancelled."
+			}
+			return m, nil
+		}
+
 		switch {
 		case key.Matches(msg, m.keyMap.Quit):
 			return m, tea.Quit
@@ -90,26 +163,55 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.help.ShowAll = !m.help.ShowAll
 			return m, nil
 		case key.Matches(msg, m.keyMap.Select):
-			if m.snapshotNew == -1 {
-				m.snapshotNew = m.table.Cursor()
-				m.table.SetRows(m.UpdateRows())
-				m.table.GotoBottom()
-			} else if m.snapshotNew != m.table.Cursor() && m.table.Cursor() < m.snapshotNew {
-				m.snapshotOld = m.table.Cursor()
-				m.table.SetRows(m.UpdateRows())
+			index := m.cursorSnapshot()
+			if index == -1 {
+				return m, nil
+			}
+			m.toggleSelection(index)
+			m.table.SetRows(m.UpdateRows())
+			m.warning, m.forced = "", false
+			return m, nil
+		case key.Matches(msg, m.keyMap.Timeline):
+			index := m.cursorSnapshot()
+			if index == -1 {
+				return m, nil
 			}
+			if m.timeline[index] {
+				delete(m.timeline, index)
+			} else {
+				m.timeline[index] = true
+			}
+			m.table.SetRows(m.UpdateRows())
+			m.warning, m.forced = "", false
 			return m, nil
+		case key.Matches(msg, m.keyMap.LatestPrevious):
+			return m.openPreset(restic.RefPrevious)
+		case key.Matches(msg, m.keyMap.LatestWeek):
+			return m.openPreset("1w")
+		case key.Matches(msg, m.keyMap.HostPrevious):
+			return m.openPreset(restic.RefHostPrevious)
+		case key.Matches(msg, m.keyMap.Query):
+			m.queryInput.SetValue(m.query)
+			m.queryInput.CursorEnd()
+			return m, m.queryInput.Focus()
+		case key.Matches(msg, m.keyMap.GroupByHost):
+			m.groupByHost = !m.groupByHost
+			m.table.SetRows(m.UpdateRows())
+			m.table.GotoBottom()
+			return m, nil
+		case key.Matches(msg, m.keyMap.Growth):
+			growthModel := growth.InitialModel(m, m.width, m.height, m.source, m.growthSnapshots(), m.options.Moves)
+			return growthModel, growthModel.Init()
 		case key.Matches(msg, m.keyMap.Clear):
 			m.snapshotNew = -1
 			m.snapshotOld = -1
+			m.timeline = make(map[int]bool)
+			m.warning, m.forced = "", false
+			m.err = nil
 			m.table.SetRows(m.UpdateRows())
 			return m, nil
 		case key.Matches(msg, m.keyMap.Accept):
-			if m.snapshotNew == -1 || m.snapshotOld == -1 {
-				return m, nil
-			}
-			m.waiting = true
-			return m, tea.Batch(m.spinner.Tick, m.LoadSnapshots)
+			return m.accept()
 		}
 	}
 	m.table, cmd = m.table.Update(msg)
@@ -122,20 +224,40 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m Model) View() string {
 	var output strings.Builder
 
+	if m.queryInput.Focused() {
+		output.WriteString(m.queryInput.View())
+		output.WriteString("\n")
+	} else if m.query != "" {
+		output.WriteString(fmt.Sprintf("Query: %s (%d/%d)\n", m.query, len(m.visibleSnapshots()), len(m.snapshots)))
+	}
 	output.WriteString(m.table.View())
 	output.WriteString("\n")
 
 	var footer string
 	if m.snapshotNew != -1 {
-		footer += fmt.Sprintf("\n%s %s", "[1]", m.snapshots[m.snapshotNew].Path)
+		footer += fmt.Sprintf("\n%s %s", "[1]", snapshotLabel(m.snapshots[m.snapshotNew]))
 	}
 	if m.snapshotOld != -1 {
-		footer += fmt.Sprintf("\n%s %s", "[2]", m.snapshots[m.snapshotOld].Path)
+		footer += fmt.Sprintf("\n%s %s", "[2]", snapshotLabel(m.snapshots[m.snapshotOld]))
+	}
+	if len(m.timeline) > 0 {
+		footer += fmt.Sprintf("\n%s %d snapshots", "[•]", len(m.timeline))
 	}
 	output.WriteString(footer)
 
+	if m.warning != "" {
+		output.WriteString("\n\n")
+		output.WriteString(warningStyle.Render(m.warning))
+	}
+
+	if m.err != nil {
+		output.WriteString("\n\n")
+		output.WriteString(warningStyle.Render(fmt.Sprintf("Error: %v", m.err)))
+	}
+
 	if m.waiting {
-		output.WriteString(fmt.Sprintf("\n\n%s Loading repositories\n", m.spinner.View()))
+		output.WriteString("\n\n")
+		output.WriteString(m.progressView())
 	}
 
 	output.WriteString("\n")
@@ -147,56 +269,209 @@ func (m Model) View() string {
 type SnapshotSelectionMsg struct {
 	Newer *restic.DirData
 	Older *restic.DirData
+	Tree  *compare.Node
 }
 
-func GetEntriesAsync(dirPath string, c chan []*restic.DirData, e chan error) {
-	rootNode, err := restic.GetDirEntries(dirPath)
-	if err != nil {
-		e <- fmt.Errorf("error getting dir newEntries: %w", err)
-		return
+type TimelineSelectionMsg struct {
+	Snapshots []restic.Snapshot // Oldest first
+	Tree      *timeline.Node
+}
+
+// snapshotLabel returns the mount directory of s, or its id and date if not mounted
+func snapshotLabel(s restic.Snapshot) string {
+	if s.Path != "" {
+		return s.Path
+	}
+	return fmt.Sprintf("%s %s", s.ShortId, s.Date.Format("2006-01-02 15:04:05"))
+}
+
+// accept loads the selected snapshots
+func (m Model) accept() (tea.Model, tea.Cmd) {
+	// A timeline takes precedence over the pair of snapshots
+	if len(m.timeline) >= 2 {
+		if !m.checkComparable(m.timelineSnapshots()...) {
+			return m, nil
+		}
+		return m, m.LoadTimeline()
+	}
+	if m.snapshotNew == -1 || m.snapshotOld == -1 {
+		return m, nil
+	}
+	if !m.checkComparable(m.snapshots[m.snapshotNew], m.snapshots[m.snapshotOld]) {
+		return m, nil
 	}
+	return m, m.LoadSnapshots()
+}
+
+// growthSnapshots returns the snapshots of the timeline, or the ones of
+// the last days matching the query if less than two were added to it
+func (m Model) growthSnapshots() []restic.Snapshot {
+	if len(m.timeline) < 2 {
+		q := parseQuery(m.query)
+		var matching []restic.Snapshot
+		for _, s := range m.snapshots {
+			if q.match(s) {
+				matching = append(matching, s)
+			}
+		}
+		return growth.Range(matching, time.Now().Add(-growth.DefaultSince), "")
+	}
+	return m.timelineSnapshots()
+}
 
-	c <- []*restic.DirData{rootNode}
+// timelineSnapshots returns the snapshots of the timeline, oldest first
+func (m Model) timelineSnapshots() []restic.Snapshot {
+	var snapshots []restic.Snapshot
+	for i, s := range m.snapshots {
+		if m.timeline[i] {
+			snapshots = append(snapshots, s)
+		}
+	}
+	return snapshots
+}
+
+// checkComparable reports if the snapshots can be compared. Snapshots
+// from different hosts or paths are only compared after a warning.
+func (m *Model) checkComparable(snapshots ...restic.Snapshot) bool {
+	err := restic.CheckComparable(snapshots...)
+	if err == nil || m.forced {
+		return true
+	}
+	m.warning = fmt.Sprintf("Warning: %v. Press enter again to compare them anyway.", err)
+	m.forced = true
+	return false
 }
 
-func (m Model) LoadSnapshots() tea.Msg {
-	newChan := make(chan []*restic.DirData, 1)
-	newErrChan := make(chan error, 1)
-	oldChan := make(chan []*restic.DirData, 1)
-	oldErrChan := make(chan error, 1)
+// toggleSelection selects or unselects the snapshot index. A third
+// snapshot replaces [2]. The newer of the two is [1].
+func (m *Model) toggleSelection(index int) {
+	switch {
+	case index == m.snapshotNew:
+		m.snapshotNew, m.snapshotOld = m.snapshotOld, -1
+	case index == m.snapshotOld:
+		m.snapshotOld = -1
+	case m.snapshotNew == -1:
+		m.snapshotNew = index
+	default:
+		m.snapshotOld = index
+	}
+	m.orderSelection()
+}
+
+// orderSelection makes [1] the newer of the selected snapshots, by date
+func (m *Model) orderSelection() {
+	if m.snapshotNew == -1 || m.snapshotOld == -1 {
+		return
+	}
+	if m.snapshots[m.snapshotOld].Date.After(m.snapshots[m.snapshotNew].Date) {
+		m.snapshotNew, m.snapshotOld = m.snapshotOld, m.snapshotNew
+	}
+}
+
+// openPreset compares a snapshot with the older one referenced by ref,
+// see restic.FindOlder. The newer snapshot is the one under the cursor
+// for "host-previous", else the latest one shown.
+func (m Model) openPreset(ref string) (tea.Model, tea.Cmd) {
+	visible := m.visibleSnapshots()
+	if len(visible) == 0 {
+		return m, nil
+	}
+	var shown []restic.Snapshot
+	newer := m.snapshots[visible[0]]
+	for _, index := range visible {
+		s := m.snapshots[index]
+		shown = append(shown, s)
+		if s.Date.After(newer.Date) {
+			newer = s
+		}
+	}
+	if ref == restic.RefHostPrevious {
+		if index := m.cursorSnapshot(); index != -1 {
+			newer = m.snapshots[index]
+		}
+	}
 
-	go GetEntriesAsync(m.snapshots[m.snapshotNew].Path, newChan, newErrChan)
-	go GetEntriesAsync(m.snapshots[m.snapshotOld].Path, oldChan, oldErrChan)
+	older, err := restic.FindOlder(shown, newer, ref)
+	if err != nil {
+		m.warning = fmt.Sprintf("Warning: %v.", err)
+		return m, nil
+	}
+	m.snapshotNew, m.snapshotOld = m.indexOf(newer), m.indexOf(older)
+	m.warning, m.forced = "", false
+	m.table.SetRows(m.UpdateRows())
+	return m.accept()
+}
 
-	var newEntries []*restic.DirData
-	var oldEntries []*restic.DirData
-	for i := 0; i < 2; i++ {
-		select {
-		case entries := <-newChan:
-			newEntries = entries
-		case err := <-newErrChan:
-			panic(fmt.Sprintf("Failed to get new entries: %v", err))
-		case entries := <-oldChan:
-			oldEntries = entries
-		case err := <-oldErrChan:
-			panic(fmt.Sprintf("Failed to get old entries: %v", err))
+// indexOf returns the index of s in the snapshots, -1 if not found
+func (m Model) indexOf(s restic.Snapshot) int {
+	for i, other := range m.snapshots {
+		if other.Id == s.Id {
+			return i
 		}
 	}
+	return -1
+}
 
-	return SnapshotSelectionMsg{
-		Newer: newEntries[0],
-		Older: oldEntries[0],
+// updateQuery handles the keys while the query bar has focus
+func (m Model) updateQuery(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.queryInput.Blur()
+		m.query = strings.TrimSpace(m.queryInput.Value())
+		m.table.SetRows(m.UpdateRows())
+		m.table.GotoBottom()
+		return m, nil
+	case tea.KeyEsc:
+		m.queryInput.Blur()
+		return m, nil
 	}
+	var cmd tea.Cmd
+	m.queryInput, cmd = m.queryInput.Update(msg)
+	return m, cmd
+}
 
+// visibleSnapshots returns the indexes of the snapshots shown as rows,
+// in the order of the rows
+func (m Model) visibleSnapshots() []int {
+	q := parseQuery(m.query)
+	var visible []int
+	for index, s := range m.snapshots {
+		// The selected snapshots are always shown
+		if index == m.snapshotNew || index == m.snapshotOld || m.timeline[index] || q.match(s) {
+			visible = append(visible, index)
+		}
+	}
+	if m.groupByHost {
+		// The snapshots stay sorted by date within a host
+		sort.SliceStable(visible, func(i, j int) bool {
+			return m.snapshots[visible[i]].Hostname < m.snapshots[visible[j]].Hostname
+		})
+	}
+	return visible
+}
+
+// cursorSnapshot returns the index of the snapshot under the cursor,
+// -1 if there are no rows
+func (m Model) cursorSnapshot() int {
+	visible := m.visibleSnapshots()
+	if m.table.Cursor() < 0 || m.table.Cursor() >= len(visible) {
+		return -1
+	}
+	return visible[m.table.Cursor()]
 }
+
 func (m Model) UpdateRows() []table.Row {
 	var t []table.Row
 
-	for index, s := range m.snapshots {
-		// Don't show rows after [1]
-		if m.snapshotNew >= 0 && index > m.snapshotNew {
-			break
+	prevHost := ""
+	for i, index := range m.visibleSnapshots() {
+		s := m.snapshots[index]
+		// Grouped rows show the host once
+		host := s.Hostname
+		if m.groupByHost && i > 0 && host == prevHost {
+			host = ""
 		}
+		prevHost = s.Hostname
 
 		checked := " "
 		switch index {
@@ -204,11 +479,18 @@ func (m Model) UpdateRows() []table.Row {
 			checked = "1"
 		case m.snapshotOld:
 			checked = "2"
+		default:
+			if m.timeline[index] {
+				checked = "•"
+			}
 		}
 		t = append(t, []string{
 			checked,
-			s.Id,
+			s.ShortId,
 			s.Date.Format("2006-01-02 15:04:05"),
+			host,
+			strings.Join(s.Tags, ","),
+			strings.Join(s.Paths, ","),
 			s.SizeStr,
 		})
 	}
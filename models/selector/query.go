@@ -0,0 +1,70 @@
+package selector
+
+import (
+	"strings"
+
+	"gestic/restic"
+)
+
+// query filters the snapshots. It is a list of words, optionally
+// prefixed by "host:", "tag:" or "path:". A snapshot matches if it
+// matches all the words. Words without a prefix match any of them.
+type query struct {
+	hosts []string
+	tags  []string
+	paths []string
+	words []string
+}
+
+func parseQuery(s string) query {
+	var q query
+	for _, word := range strings.Fields(strings.ToLower(s)) {
+		switch {
+		case strings.HasPrefix(word, "host:"):
+			q.hosts = append(q.hosts, strings.TrimPrefix(word, "host:"))
+		case strings.HasPrefix(word, "tag:"):
+			q.tags = append(q.tags, strings.TrimPrefix(word, "tag:"))
+		case strings.HasPrefix(word, "path:"):
+			q.paths = append(q.paths, strings.TrimPrefix(word, "path:"))
+		default:
+			q.words = append(q.words, word)
+		}
+	}
+	return q
+}
+
+// match reports if s matches all the words of the query
+func (q query) match(s restic.Snapshot) bool {
+	host := []string{s.Hostname}
+	for _, h := range q.hosts {
+		if !anyContains(host, h) {
+			return false
+		}
+	}
+	for _, t := range q.tags {
+		if !anyContains(s.Tags, t) {
+			return false
+		}
+	}
+	for _, p := range q.paths {
+		if !anyContains(s.Paths, p) {
+			return false
+		}
+	}
+	for _, w := range q.words {
+		if !anyContains(host, w) && !anyContains(s.Tags, w) && !anyContains(s.Paths, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// anyContains reports if one of values contains sub, ignoring the case
+func anyContains(values []string, sub string) bool {
+	for _, v := range values {
+		if strings.Contains(strings.ToLower(v), sub) {
+			return true
+		}
+	}
+	return false
+}
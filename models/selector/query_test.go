@@ -0,0 +1,43 @@
+package selector
+
+import (
+	"testing"
+
+	"gestic/restic"
+)
+
+func TestParseQuery(t *testing.T) {
+	s := restic.Snapshot{
+		Hostname: "Laptop",
+		Tags:     []string{"daily", "Photos"},
+		Paths:    []string{"/home/ana", "/etc"},
+	}
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"", true},
+		{"   ", true},
+		{"laptop", true},
+		{"LAP", true},
+		{"photos", true},
+		{"home", true},
+		{"server", false},
+		{"host:lap", true},
+		{"host:daily", false},
+		{"tag:daily", true},
+		{"tag:laptop", false},
+		{"path:/etc", true},
+		{"path:photos", false},
+		{"host:laptop tag:photos path:ana", true},
+		{"host:laptop tag:weekly", false},
+		{"daily etc", true},
+		{"daily server", false},
+		{"tag:", true},
+	}
+	for _, tt := range tests {
+		if got := parseQuery(tt.query).match(s); got != tt.want {
+			t.Errorf("parseQuery(%q).match = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
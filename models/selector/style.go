@@ -17,3 +17,6 @@ var tableStyles = table.Styles{
 		// Background(lipgloss.Color("#fcfcfc")),
 	Cell: lipgloss.NewStyle().Padding(0, 1),
 }
+
+var warningStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#fdbc4b"))
@@ -0,0 +1,55 @@
+package timeline
+
+import "github.com/charmbracelet/bubbles/key"
+
+type keymap struct {
+	NextDir key.Binding
+	PrevDir key.Binding
+	Root    key.Binding
+	Sort    key.Binding
+	Quit    key.Binding
+	Help    key.Binding
+}
+
+func (k keymap) ShortHelp() []key.Binding {
+	return []key.Binding{k.NextDir, k.PrevDir, k.Sort}
+}
+
+func (k keymap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.NextDir, k.Help},
+		{k.PrevDir, k.Quit},
+		{k.Root, k.Sort},
+	}
+}
+
+// DefaultKeyMap returns the keys of the timeline, the same as the
+// compare view ones
+func DefaultKeyMap() keymap {
+	return keymap{
+		Help: key.NewBinding(
+			key.WithKeys("?"),
+			key.WithHelp("?", "toggle help"),
+		),
+		Quit: key.NewBinding(
+			key.WithKeys("ctrl+c", "q"),
+			key.WithHelp("ctrl+c", "quit"),
+		),
+		NextDir: key.NewBinding(
+			key.WithKeys("l", "right", "enter"),
+			key.WithHelp("l/right", "Open"),
+		),
+		PrevDir: key.NewBinding(
+			key.WithKeys("h", "left", "backspace"),
+			key.WithHelp("h/left", "Back"),
+		),
+		Root: key.NewBinding(
+			key.WithKeys("~"),
+			key.WithHelp("~", "Go to root"),
+		),
+		Sort: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "Sort"),
+		),
+	}
+}
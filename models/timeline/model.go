@@ -0,0 +1,231 @@
+package timeline
+
+import (
+	"fmt"
+	"strings"
+
+	"gestic/models/compare"
+	"gestic/restic"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dustin/go-humanize"
+)
+
+// ViewportHeight is the number of rows of the table
+const ViewportHeight = 12
+
+// sizeWidth is the width of the size column of a snapshot
+const sizeWidth = 11
+
+// growthWidth is the width of the growth column
+const growthWidth = 10
+
+// Model shows the size of the entries of a directory in each of the
+// selected snapshots
+type Model struct {
+	prevModel tea.Model
+	help      help.Model
+	keyMap    keymap
+	width     int
+	height    int
+
+	snapshots []restic.Snapshot // Oldest first
+	node      *Node
+	rows      []*Node
+	sort      SortMode
+	table     table.Model
+}
+
+// InitialModel returns the timeline of node, a directory of the tree of
+// snapshots
+func InitialModel(prevModel tea.Model, width, height int, node *Node, snapshots []restic.Snapshot, sort SortMode) *Model {
+	m := &Model{
+		prevModel: prevModel,
+		help:      help.New(),
+		keyMap:    DefaultKeyMap(),
+		width:     width,
+		height:    height,
+		snapshots: snapshots,
+		node:      node,
+		sort:      sort,
+		table: table.New(
+			table.WithFocused(true),
+			table.WithHeight(ViewportHeight),
+			table.WithStyles(tableStyles),
+		),
+	}
+	m.resizeTable()
+	m.refreshRows(nil)
+	return m
+}
+
+func (m *Model) Init() tea.Cmd {
+	return tea.Batch(
+		tea.ClearScreen,
+		func() tea.Msg { return tea.WindowSizeMsg{Width: m.width, Height: m.height} },
+	)
+}
+
+func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.resizeTable()
+		return m, nil
+
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keyMap.Quit):
+			return m, tea.Quit
+
+		case key.Matches(msg, m.keyMap.Help):
+			m.help.ShowAll = !m.help.ShowAll
+			return m, nil
+
+		case key.Matches(msg, m.keyMap.Sort):
+			m.sort = m.sort.Next()
+			m.refreshRows(m.selectedNode())
+			return m, nil
+
+		case key.Matches(msg, m.keyMap.NextDir):
+			nextNode := m.selectedNode()
+			// Don't try to advance if is an empty directory or a file
+			if nextNode == nil || len(nextNode.Children) == 0 {
+				return m, nil
+			}
+			nextModel := InitialModel(m, m.width, m.height, nextNode, m.snapshots, m.sort)
+			return nextModel, nextModel.Init()
+
+		case key.Matches(msg, m.keyMap.PrevDir):
+			if m.node.Parent != nil {
+				return m.goBackTo(m.node.Parent)
+			}
+			return m, nil
+
+		case key.Matches(msg, m.keyMap.Root):
+			return m.goBackTo(m.node.Root())
+		}
+	}
+
+	var cmd tea.Cmd
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+// goBackTo returns the model of the prevModel chain showing target,
+// with the sort of the current one
+func (m *Model) goBackTo(target *Node) (tea.Model, tea.Cmd) {
+	prev, ok := compare.FindPrev(m, target)
+	if !ok {
+		return m, nil
+	}
+	if prev.sort != m.sort {
+		prev.sort = m.sort
+		prev.refreshRows(prev.selectedNode())
+	}
+	return prev, compare.ResizeCmd(m.width, m.height)
+}
+
+func (m *Model) Dir() *Node {
+	return m.node
+}
+
+func (m *Model) Prev() tea.Model {
+	return m.prevModel
+}
+
+func (m *Model) View() string {
+	var output strings.Builder
+
+	output.WriteString(m.headerView())
+	output.WriteString("\n")
+	output.WriteString(m.table.View())
+	output.WriteString("\n")
+
+	first, last := m.snapshots[0], m.snapshots[len(m.snapshots)-1]
+	output.WriteString(fmt.Sprintf("%d snapshots from %s (%s) to %s (%s)\n",
+		len(m.snapshots),
+		first.ShortId, first.Date.Format("2006-01-02 15:04"),
+		last.ShortId, last.Date.Format("2006-01-02 15:04")))
+	output.WriteString("\n")
+	output.WriteString(m.help.View(m.keyMap))
+
+	return output.String()
+}
+
+func (m *Model) headerView() string {
+	dir := "/" + m.node.RelPath
+	header := fmt.Sprintf("Timeline: %s (%s)  Sort: %s", dir, m.node.GrowthReadable(), m.sort)
+	return headerStyle.Render(header)
+}
+
+// resizeTable gives the name column the width left by the size columns
+func (m *Model) resizeTable() {
+	sparkWidth := max(len(m.snapshots), len("Trend"))
+	// The cells have a padding of 1 on each side
+	used := (len(m.snapshots)*(sizeWidth+2) + growthWidth + 2 + sparkWidth + 2)
+	nameWidth := max(m.width-used-2, 20)
+
+	columns := []table.Column{{Title: "Name", Width: nameWidth}}
+	for _, s := range m.snapshots {
+		columns = append(columns, table.Column{Title: s.Date.Format("01-02 15:04"), Width: sizeWidth})
+	}
+	columns = append(columns,
+		table.Column{Title: "Growth", Width: growthWidth},
+		table.Column{Title: "Trend", Width: sparkWidth},
+	)
+
+	// The rows must have as many cells as the columns
+	cursor := m.table.Cursor()
+	m.table.SetRows(nil)
+	m.table.SetColumns(columns)
+	m.updateTable(cursor)
+}
+
+// refreshRows sorts the children of the current node. The cursor stays
+// on selected if it is still a row.
+func (m *Model) refreshRows(selected *Node) {
+	m.rows = SortNodes(m.node.Children, m.sort)
+	cursor := 0
+	for i, r := range m.rows {
+		if r == selected {
+			cursor = i
+			break
+		}
+	}
+	m.updateTable(cursor)
+}
+
+func (m *Model) updateTable(cursor int) {
+	rows := make([]table.Row, 0, len(m.rows))
+	for _, r := range m.rows {
+		row := table.Row{r.PathReadable()}
+		for i := range r.Entries {
+			size := "-"
+			if r.Entries[i] != nil {
+				size = humanize.Bytes(uint64(r.Size(i)))
+			}
+			row = append(row, fmt.Sprintf("%*s", sizeWidth, size))
+		}
+		row = append(row, r.GrowthReadable(), Sparkline(r))
+		rows = append(rows, row)
+	}
+	m.table.SetRows(rows)
+
+	// table.SetCursor doesn't scroll, moving the cursor does
+	m.table.GotoTop()
+	m.table.MoveDown(max(min(cursor, len(rows)-1), 0))
+}
+
+// selectedNode returns the node under the cursor, nil if there are no rows
+func (m *Model) selectedNode() *Node {
+	if m.table.Cursor() < 0 || m.table.Cursor() >= len(m.rows) {
+		return nil
+	}
+	return m.rows[m.table.Cursor()]
+}
@@ -0,0 +1,42 @@
+package timeline
+
+import "gestic/models/compare"
+
+// SortMode is the order of the rows in the timeline
+type SortMode int
+
+const (
+	SortByGrowth  SortMode = iota // Growth from the oldest snapshot, descending
+	SortByNewSize                 // Size in the newest snapshot, descending
+	SortByName                    // Name, ascending
+)
+
+var sortModeNames = []string{"growth", "newest size", "name"}
+
+func (s SortMode) String() string {
+	return sortModeNames[s]
+}
+
+// Next returns the mode after s, wrapping to the first one
+func (s SortMode) Next() SortMode {
+	return (s + 1) % SortMode(len(sortModeNames))
+}
+
+// SortNodes returns a copy of nodes sorted by mode
+func SortNodes(nodes []*Node, mode SortMode) []*Node {
+	switch mode {
+	case SortByNewSize:
+		return compare.SortStable(nodes, func(a, b *Node) bool {
+			last := len(a.Entries) - 1
+			return a.Size(last) > b.Size(last)
+		})
+	case SortByName:
+		return compare.SortStable(nodes, func(a, b *Node) bool {
+			return a.Name < b.Name
+		})
+	default:
+		return compare.SortStable(nodes, func(a, b *Node) bool {
+			return a.Growth > b.Growth
+		})
+	}
+}
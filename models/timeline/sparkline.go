@@ -0,0 +1,35 @@
+package timeline
+
+import "strings"
+
+// sparkLevels are the bars of a sparkline, from the lowest to the highest
+var sparkLevels = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline returns a bar per snapshot, scaled between the smallest and
+// the largest size of n. The snapshots without the entry are blank.
+func Sparkline(n *Node) string {
+	var low, high int64 = -1, 0
+	for i, e := range n.Entries {
+		if e == nil {
+			continue
+		}
+		if low < 0 || n.Size(i) < low {
+			low = n.Size(i)
+		}
+		high = max(high, n.Size(i))
+	}
+
+	var spark strings.Builder
+	for i, e := range n.Entries {
+		switch {
+		case e == nil:
+			spark.WriteRune(' ')
+		case high == low:
+			spark.WriteRune(sparkLevels[0])
+		default:
+			level := (n.Size(i) - low) * int64(len(sparkLevels)-1) / (high - low)
+			spark.WriteRune(sparkLevels[level])
+		}
+	}
+	return spark.String()
+}
@@ -0,0 +1,115 @@
+package timeline
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"gestic/restic"
+
+	"github.com/dustin/go-humanize"
+)
+
+// Node is an entry of the tree of several snapshots
+type Node struct {
+	Name     string            // Name relative to the parent
+	RelPath  string            // Path relative to the snapshot root
+	Entries  []*restic.DirData // Entry in each snapshot, oldest first, nil if missing
+	Growth   int64             // Size in the newest snapshot - size in the oldest
+	Parent   *Node
+	Children []*Node // Sorted by Growth, descending
+}
+
+// BuildTree pairs the entries of the roots by name, recursively. The
+// roots are the snapshots from the oldest to the newest.
+func BuildTree(roots []*restic.DirData) *Node {
+	root := buildNode(nil, "/", roots)
+	root.RelPath = ""
+	return root
+}
+
+func buildNode(parent *Node, name string, entries []*restic.DirData) *Node {
+	n := &Node{
+		Name:    name,
+		Entries: entries,
+		Parent:  parent,
+	}
+	if parent != nil {
+		n.RelPath = path.Join(parent.RelPath, name)
+	}
+	n.Growth = n.Size(len(entries)-1) - n.Size(0)
+
+	// Pair the children by name, in the order they first appear
+	var names []string
+	children := make(map[string][]*restic.DirData)
+	for i, e := range entries {
+		if e == nil {
+			continue
+		}
+		for _, c := range e.Children {
+			childName := filepath.Base(c.Path)
+			if _, ok := children[childName]; !ok {
+				children[childName] = make([]*restic.DirData, len(entries))
+				names = append(names, childName)
+			}
+			children[childName][i] = c
+		}
+	}
+
+	n.Children = make([]*Node, 0, len(names))
+	for _, childName := range names {
+		n.Children = append(n.Children, buildNode(n, childName, children[childName]))
+	}
+	sort.SliceStable(n.Children, func(i, j int) bool {
+		return n.Children[i].Growth > n.Children[j].Growth
+	})
+	return n
+}
+
+// Size returns the size of the entry in the snapshot i, 0 if missing
+func (n *Node) Size(i int) int64 {
+	if n.Entries[i] == nil {
+		return 0
+	}
+	return n.Entries[i].Size
+}
+
+// IsDir reports if the entry is a directory in any snapshot
+func (n *Node) IsDir() bool {
+	for _, e := range n.Entries {
+		if e != nil && e.IsDir {
+			return true
+		}
+	}
+	return false
+}
+
+// PathReadable returns the readable name of the entry in the newest
+// snapshot it is found in
+func (n *Node) PathReadable() string {
+	for i := len(n.Entries) - 1; i >= 0; i-- {
+		if n.Entries[i] != nil {
+			return n.Entries[i].PathReadable
+		}
+	}
+	return n.Name
+}
+
+// GrowthReadable returns the signed, human-readable growth
+func (n *Node) GrowthReadable() string {
+	signStr := "+"
+	if n.Growth < 0 {
+		signStr = "-"
+	}
+	return fmt.Sprintf("%s%s", signStr, humanize.Bytes(uint64(max(n.Growth, -n.Growth))))
+}
+
+// Root returns the root of the tree n belongs to
+func (n *Node) Root() *Node {
+	root := n
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	return root
+}
@@ -0,0 +1,76 @@
+package restic
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// dumpReader streams a file from `restic dump`
+type dumpReader struct {
+	io.ReadCloser
+	cmd    *exec.Cmd
+	stderr *bytes.Buffer
+	eof    bool
+}
+
+func (r *dumpReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if err == io.EOF {
+		r.eof = true
+	}
+	return n, err
+}
+
+// Close waits for restic to exit. If the file wasn't read until the
+// end, restic is stopped instead.
+func (r *dumpReader) Close() error {
+	if !r.eof {
+		_ = r.cmd.Process.Kill()
+		_ = r.cmd.Wait()
+		return nil
+	}
+	if err := r.cmd.Wait(); err != nil {
+		return fmt.Errorf("error return from restic command: %w: %s", err, strings.TrimSpace(r.stderr.String()))
+	}
+	return nil
+}
+
+// DumpFile returns the content of a file of a snapshot, streamed from
+// `restic dump`. Closing the reader before the end stops restic.
+func DumpFile(repoPath, snapshotId, filePath string) (io.ReadCloser, error) {
+	args := []string{"-r", repoPath, "dump", snapshotId, filePath}
+	cmd := exec.Command("restic", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("can't execute restic command: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("can't execute restic command: %w", err)
+	}
+	return &dumpReader{ReadCloser: stdout, cmd: cmd, stderr: &stderr}, nil
+}
+
+// HashEntry returns the SHA-256 of the content of a file of a snapshot
+func HashEntry(source SnapshotSource, s Snapshot, d *DirData) (string, error) {
+	r, err := source.Open(s, d.Path)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	_, copyErr := io.Copy(h, r)
+	if err := r.Close(); err != nil {
+		return "", err
+	}
+	if copyErr != nil {
+		return "", fmt.Errorf("can't read %s: %w", d.Path, copyErr)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,86 @@
+package restic
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoSnapshots is returned when the repository has no snapshots.
+var ErrNoSnapshots = errors.New("expected at least 1 snapshot")
+
+// UnparseableOutputError is returned when the output of a restic
+// command is not in the expected format.
+type UnparseableOutputError struct {
+	Command string // restic subcommand, e.g. "snapshots"
+	Err     error
+}
+
+func (e *UnparseableOutputError) Error() string {
+	return fmt.Sprintf("can't parse output of 'restic %s': %v", e.Command, e.Err)
+}
+
+func (e *UnparseableOutputError) Unwrap() error {
+	return e.Err
+}
+
+// UnknownMountEntryError is returned for an entry of the snapshots
+// directory that does not belong to any snapshot.
+type UnknownMountEntryError struct {
+	Name string // Entry name in the snapshots directory
+	Err  error  // Parse error, nil if the name is valid
+}
+
+func (e *UnknownMountEntryError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("unknown entry %q in the snapshots directory: %v", e.Name, e.Err)
+	}
+	return fmt.Sprintf("entry %q in the snapshots directory matches no snapshot", e.Name)
+}
+
+func (e *UnknownMountEntryError) Unwrap() error {
+	return e.Err
+}
+
+// SnapshotWithoutFolderError is returned when a snapshot listed by
+// restic has no directory in the mount point.
+type SnapshotWithoutFolderError struct {
+	Snapshots []Snapshot
+}
+
+func (e *SnapshotWithoutFolderError) Error() string {
+	ids := ""
+	for i, s := range e.Snapshots {
+		if i > 0 {
+			ids += ", "
+		}
+		ids += s.ShortId
+	}
+	return fmt.Sprintf("snapshots without a directory in the mount point: %s", ids)
+}
+
+// MountNotPresentError is returned when the mount point does not
+// have a snapshots directory.
+type MountNotPresentError struct {
+	Path string // Expected snapshots directory
+	Err  error
+}
+
+func (e *MountNotPresentError) Error() string {
+	return fmt.Sprintf("mount point not found at %s: %v", e.Path, e.Err)
+}
+
+func (e *MountNotPresentError) Unwrap() error {
+	return e.Err
+}
+
+// MismatchError is returned when snapshots from different hosts or of
+// different paths are compared.
+type MismatchError struct {
+	Field  string   // "hosts" or "paths"
+	Values []string // Distinct values, in the order of the snapshots
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("the snapshots have different %s: %s", e.Field, strings.Join(e.Values, " / "))
+}
@@ -0,0 +1,163 @@
+package restic
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FindSnapshot returns the snapshot referenced by ref. It can be an ID
+// or a prefix of it, "latest", or "latest~N" for the N-th snapshot
+// before the latest one.
+func FindSnapshot(snapshots []Snapshot, ref string) (Snapshot, error) {
+	if len(snapshots) == 0 {
+		return Snapshot{}, ErrNoSnapshots
+	}
+
+	if ref == "latest" || strings.HasPrefix(ref, "latest~") {
+		n := 0
+		if ref != "latest" {
+			var err error
+			n, err = strconv.Atoi(strings.TrimPrefix(ref, "latest~"))
+			if err != nil || n < 0 {
+				return Snapshot{}, fmt.Errorf("invalid snapshot reference %q", ref)
+			}
+		}
+		sorted := make([]Snapshot, len(snapshots))
+		copy(sorted, snapshots)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].Date.Before(sorted[j].Date)
+		})
+		if n >= len(sorted) {
+			return Snapshot{}, fmt.Errorf("%q is out of range: the repository has %d snapshots", ref, len(sorted))
+		}
+		return sorted[len(sorted)-1-n], nil
+	}
+
+	var found []Snapshot
+	for _, s := range snapshots {
+		if strings.HasPrefix(s.Id, ref) {
+			found = append(found, s)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return Snapshot{}, fmt.Errorf("no snapshot matches %q", ref)
+	case 1:
+		return found[0], nil
+	default:
+		return Snapshot{}, fmt.Errorf("%q matches %d snapshots, use a longer prefix", ref, len(found))
+	}
+}
+
+// Older snapshot references, relative to the newer snapshot
+const (
+	RefPrevious     = "previous"      // The snapshot before
+	RefHostPrevious = "host-previous" // The snapshot before from the same host
+)
+
+// FindOlder returns the snapshot referenced by ref, which can also be
+// relative to newer: "previous", "host-previous", or an age like "1w"
+// for the last snapshot taken at least a week before newer. An ID
+// prefix that reads like an age, such as "1234567d", is an ID.
+func FindOlder(snapshots []Snapshot, newer Snapshot, ref string) (Snapshot, error) {
+	var match func(s Snapshot) bool
+	switch ref {
+	case RefPrevious:
+		match = func(s Snapshot) bool { return true }
+	case RefHostPrevious:
+		match = func(s Snapshot) bool { return s.Hostname == newer.Hostname }
+	default:
+		isPrefix := func(s Snapshot) bool { return strings.HasPrefix(s.Id, ref) }
+		if slices.ContainsFunc(snapshots, isPrefix) {
+			return FindSnapshot(snapshots, ref)
+		}
+		age, err := ParseAge(ref)
+		if err != nil {
+			return FindSnapshot(snapshots, ref)
+		}
+		match = func(s Snapshot) bool { return !s.Date.After(newer.Date.Add(-age)) }
+	}
+
+	var found *Snapshot
+	for i, s := range snapshots {
+		if s.Id == newer.Id || !s.Date.Before(newer.Date) || !match(s) {
+			continue
+		}
+		if found == nil || s.Date.After(found.Date) {
+			found = &snapshots[i]
+		}
+	}
+	if found == nil {
+		return Snapshot{}, fmt.Errorf("no snapshot matches %q before %s", ref, newer.ShortId)
+	}
+	return *found, nil
+}
+
+// FindPair returns the snapshots referenced by newRef, see FindSnapshot,
+// and oldRef, see FindOlder. The old one must be another snapshot, not
+// taken after the new one.
+func FindPair(snapshots []Snapshot, newRef, oldRef string) (Snapshot, Snapshot, error) {
+	newer, err := FindSnapshot(snapshots, newRef)
+	if err != nil {
+		return Snapshot{}, Snapshot{}, fmt.Errorf("new snapshot: %w", err)
+	}
+	older, err := FindOlder(snapshots, newer, oldRef)
+	if err != nil {
+		return Snapshot{}, Snapshot{}, fmt.Errorf("old snapshot: %w", err)
+	}
+	if newer.Id == older.Id {
+		return Snapshot{}, Snapshot{}, fmt.Errorf("the new and old snapshots are the same snapshot %s", newer.ShortId)
+	}
+	if older.Date.After(newer.Date) {
+		return Snapshot{}, Snapshot{}, fmt.Errorf("the old snapshot %s (%s) is newer than %s (%s), swap them",
+			older.ShortId, older.Date.Format("2006-01-02 15:04"), newer.ShortId, newer.Date.Format("2006-01-02 15:04"))
+	}
+	return newer, older, nil
+}
+
+// ParseAge reads an age in days ("30d"), weeks ("2w") or any unit of
+// time.ParseDuration ("12h")
+func ParseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return d, nil
+}
+
+// CheckComparable returns a *MismatchError if the snapshots are from
+// different hosts or have different paths
+func CheckComparable(snapshots ...Snapshot) error {
+	var hosts, paths []string
+	for _, s := range snapshots {
+		if !slices.Contains(hosts, s.Hostname) {
+			hosts = append(hosts, s.Hostname)
+		}
+		p := strings.Join(slices.Sorted(slices.Values(s.Paths)), ", ")
+		if !slices.Contains(paths, p) {
+			paths = append(paths, p)
+		}
+	}
+	if len(hosts) > 1 {
+		return &MismatchError{Field: "hosts", Values: hosts}
+	}
+	if len(paths) > 1 {
+		return &MismatchError{Field: "paths", Values: paths}
+	}
+	return nil
+}
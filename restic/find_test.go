@@ -0,0 +1,48 @@
+package restic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFindPair(t *testing.T) {
+	day := func(n int) time.Time { return time.Date(2024, 1, n, 0, 0, 0, 0, time.UTC) }
+	snapshots := []Snapshot{
+		{Id: "aaaa1111", ShortId: "aaaa1111", Date: day(1), Hostname: "h1"},
+		{Id: "bbbb2222", ShortId: "bbbb2222", Date: day(2), Hostname: "h2"},
+		{Id: "cccc3333", ShortId: "cccc3333", Date: day(3), Hostname: "h1"},
+		{Id: "1234567d", ShortId: "1234567d", Date: day(0), Hostname: "h2"},
+	}
+	tests := []struct {
+		newRef, oldRef string
+		newer, older   string // IDs, empty if an error is expected
+	}{
+		{"latest", RefPrevious, "cccc3333", "bbbb2222"},
+		{"latest", RefHostPrevious, "cccc3333", "aaaa1111"},
+		{"cccc", "2d", "cccc3333", "aaaa1111"},
+		{"cccc", "3d", "cccc3333", "1234567d"},
+		{"cccc", "1234567d", "cccc3333", "1234567d"},
+		{"latest", "12", "cccc3333", "1234567d"},
+		{"bbbb", "aaaa", "bbbb2222", "aaaa1111"},
+		{"latest", "latest", "", ""},
+		{"aaaa", "cccc", "", ""},
+		{"latest", "dddd", "", ""},
+		{"1234", RefPrevious, "", ""},
+	}
+	for _, tt := range tests {
+		newer, older, err := FindPair(snapshots, tt.newRef, tt.oldRef)
+		if tt.newer == "" {
+			if err == nil {
+				t.Errorf("FindPair(%q, %q) = %s, %s, want an error", tt.newRef, tt.oldRef, newer.Id, older.Id)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("FindPair(%q, %q) error: %v", tt.newRef, tt.oldRef, err)
+			continue
+		}
+		if newer.Id != tt.newer || older.Id != tt.older {
+			t.Errorf("FindPair(%q, %q) = %s, %s, want %s, %s", tt.newRef, tt.oldRef, newer.Id, older.Id, tt.newer, tt.older)
+		}
+	}
+}
@@ -1,9 +1,13 @@
 package restic
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/dustin/go-humanize"
 )
@@ -14,17 +18,45 @@ type DirData struct {
 	Path         string     // Full absolute path
 	PathReadable string     // Name relative to parent directory
 	Size         int64      // Size (file size or sum of children's sizes)
+	Files        int64      // Number of files under the entry (1 for files)
+	Dirs         int64      // Number of directories under the entry
 	SizeReadable string     // Human-readable size
 	IsDir        bool       // True if entry is a directory
+	ModTime      time.Time  // Modification time
+	Mode         os.FileMode
+	Hash         string // Hash of the content, empty until HashEntry reads it
+}
+
+// TreeProgress counts the entries of a snapshot tree read so far
+type TreeProgress struct {
+	Dirs  int64
+	Files int64
+	Bytes int64
+}
+
+// setStat copies the modification time and mode of info to d
+func setStat(d *DirData, info os.FileInfo) {
+	d.ModTime = info.ModTime()
+	d.Mode = info.Mode()
 }
 
 // GetDirEntries returns the immediate entries of dirPath, with directories' Children fields recursively populated.
-func GetDirEntries(root string) (*DirData, error) {
+// Directories have the size and number of entries of their subtree.
+// progress, if not nil, is called concurrently after each directory is
+// read. The walk stops with ctx.Err() when ctx is cancelled.
+func GetDirEntries(ctx context.Context, root string, progress func(TreeProgress)) (*DirData, error) {
+	if _, err := os.Stat(root); err != nil {
+		return nil, fmt.Errorf("can't read snapshot directory: %w", err)
+	}
 	maxIO := 100
 	semaphore := make(chan struct{}, maxIO)
+	var dirs, files, bytes atomic.Int64
 
 	var walk func(string) *DirData
 	walk = func(currentPath string) *DirData {
+		if ctx.Err() != nil {
+			return nil
+		}
 		semaphore <- struct{}{}
 		entries, err := os.ReadDir(currentPath)
 		<-semaphore
@@ -38,6 +70,10 @@ func GetDirEntries(root string) (*DirData, error) {
 			Path:         currentPath,
 			PathReadable: "/" + filepath.Base(currentPath),
 			Children:     make([]*DirData, 0, len(entries)),
+			IsDir:        true,
+		}
+		if info, err := os.Lstat(currentPath); err == nil {
+			setStat(node, info)
 		}
 
 		var wg sync.WaitGroup
@@ -56,6 +92,8 @@ func GetDirEntries(root string) (*DirData, error) {
 						mu.Lock()
 						node.Children = append(node.Children, childNode)
 						node.Size += childNode.Size
+						node.Files += childNode.Files
+						node.Dirs += childNode.Dirs + 1
 						mu.Unlock()
 					}
 				}(nextPath)
@@ -72,19 +110,36 @@ func GetDirEntries(root string) (*DirData, error) {
 					PathReadable: entry.Name(),
 					Size:         info.Size(),
 					SizeReadable: humanize.Bytes(uint64(info.Size())),
+					Files:        1,
 				}
+				setStat(childNode, info)
 
 				mu.Lock()
 				node.Children = append(node.Children, childNode)
 				node.Size += info.Size()
+				node.Files++
 				mu.Unlock()
+
+				files.Add(1)
+				bytes.Add(info.Size())
 			}
 		}
+		dirs.Add(1)
+		if progress != nil {
+			progress(TreeProgress{Dirs: dirs.Load(), Files: files.Load(), Bytes: bytes.Load()})
+		}
 
 		wg.Wait()
 		node.SizeReadable = humanize.Bytes(uint64(node.Size))
 		return node
 	}
 
-	return walk(root), nil
+	node := walk(root)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if node == nil {
+		return nil, fmt.Errorf("can't read snapshot directory %s", root)
+	}
+	return node, nil
 }
@@ -0,0 +1,163 @@
+package restic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// lsNode represents a line of `restic ls --json`.
+// The first line describes the snapshot and has no type.
+type lsNode struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Path string `json:"path"`
+	Size uint64 `json:"size"`
+
+	Mode  uint32    `json:"mode"`
+	MTime time.Time `json:"mtime"`
+}
+
+// lsProgressNodes is the number of nodes between progress reports
+const lsProgressNodes = 1000
+
+// GetLsEntries returns the tree of a snapshot without a mount point.
+// It reads the streamed output of `restic ls --json`, so restic must
+// be able to get the password without a terminal (e.g. RESTIC_PASSWORD).
+// restic is killed when ctx is cancelled.
+func GetLsEntries(ctx context.Context, repoPath, snapshotId string, progress func(TreeProgress)) (*DirData, error) {
+	args := []string{"-r", repoPath, "ls", "--json", snapshotId}
+	cmd := exec.CommandContext(ctx, "restic", args...)
+	// The terminal belongs to the UI, keep the errors for the message
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("can't execute restic command: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("can't execute restic command: %w", err)
+	}
+
+	root, parseErr := parseLsOutput(stdout, progress)
+	// Drain the output, otherwise restic blocks if parsing stopped early
+	_, _ = io.Copy(io.Discard, stdout)
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("error return from restic command: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	return root, nil
+}
+
+// parseLsOutput builds the same hierarchy GetDirEntries returns from
+// the nodes of `restic ls --json`. Paths are absolute, so the root is "/".
+// progress, if not nil, is called every lsProgressNodes nodes.
+func parseLsOutput(r io.Reader, progress func(TreeProgress)) (*DirData, error) {
+	root := &DirData{
+		Path:         "/",
+		PathReadable: "/",
+		IsDir:        true,
+	}
+	dirs := map[string]*DirData{"/": root}
+
+	// Parents are listed before their children, but
+	// don't rely on it and create missing directories
+	var getDir func(string) *DirData
+	getDir = func(dirPath string) *DirData {
+		if dir, ok := dirs[dirPath]; ok {
+			return dir
+		}
+		parent := getDir(path.Dir(dirPath))
+		dir := &DirData{
+			Path:         dirPath,
+			PathReadable: "/" + path.Base(dirPath),
+			IsDir:        true,
+		}
+		parent.Children = append(parent.Children, dir)
+		dirs[dirPath] = dir
+		return dir
+	}
+
+	var counts TreeProgress
+	decoder := json.NewDecoder(r)
+	for {
+		var node lsNode
+		err := decoder.Decode(&node)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, &UnparseableOutputError{Command: "ls", Err: err}
+		}
+
+		if node.Type == "dir" {
+			counts.Dirs++
+		} else if node.Type != "" {
+			counts.Files++
+			counts.Bytes += int64(node.Size)
+		}
+		if progress != nil && (counts.Dirs+counts.Files)%lsProgressNodes == 0 {
+			progress(counts)
+		}
+
+		switch node.Type {
+		case "":
+			// Snapshot description
+			continue
+		case "dir":
+			dir := getDir(node.Path)
+			dir.ModTime = node.MTime
+			dir.Mode = os.FileMode(node.Mode)
+		default:
+			parent := getDir(path.Dir(node.Path))
+			parent.Children = append(parent.Children, &DirData{
+				Path:         node.Path,
+				PathReadable: node.Name,
+				Size:         int64(node.Size),
+				SizeReadable: humanize.Bytes(node.Size),
+				Files:        1,
+				ModTime:      node.MTime,
+				Mode:         os.FileMode(node.Mode),
+			})
+		}
+	}
+
+	if progress != nil {
+		progress(counts)
+	}
+	sumTotals(root)
+	return root, nil
+}
+
+// sumTotals sets the size and entry counts of each directory to the sum of its children
+func sumTotals(node *DirData) {
+	if !node.IsDir {
+		return
+	}
+	node.Size, node.Files, node.Dirs = 0, 0, 0
+	for _, child := range node.Children {
+		sumTotals(child)
+		node.Size += child.Size
+		node.Files += child.Files
+		if child.IsDir {
+			node.Dirs += child.Dirs + 1
+		}
+	}
+	node.SizeReadable = humanize.Bytes(uint64(node.Size))
+}
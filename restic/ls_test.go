@@ -0,0 +1,56 @@
+package restic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseLsOutput(t *testing.T) {
+	output := `{"time":"2024-01-02T03:04:05Z","tree":"abc","paths":["/home"],"struct_type":"snapshot"}
+{"name":"home","type":"dir","path":"/home","mode":2147484141}
+{"name":"a.txt","type":"file","path":"/home/a.txt","size":100,"mode":420}
+{"name":"b.txt","type":"file","path":"/home/docs/b.txt","size":20}
+{"name":"docs","type":"dir","path":"/home/docs"}
+{"name":"link","type":"symlink","path":"/home/link"}
+`
+	var last TreeProgress
+	root, err := parseLsOutput(strings.NewReader(output), func(p TreeProgress) { last = p })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := (TreeProgress{Dirs: 2, Files: 3, Bytes: 120}); last != want {
+		t.Errorf("progress = %+v, want %+v", last, want)
+	}
+	if root.Path != "/" || len(root.Children) != 1 {
+		t.Fatalf("root = %+v, want / with the home directory", root)
+	}
+	home := root.Children[0]
+	if home.Path != "/home" || !home.IsDir || len(home.Children) != 3 {
+		t.Fatalf("home = %+v, want /home with 3 children", home)
+	}
+	if home.Size != 120 || home.Files != 3 || home.Dirs != 1 {
+		t.Errorf("home totals = %d bytes, %d files, %d dirs, want 120, 3, 1", home.Size, home.Files, home.Dirs)
+	}
+	if root.Size != 120 || root.Dirs != 2 {
+		t.Errorf("root totals = %d bytes, %d dirs, want 120, 2", root.Size, root.Dirs)
+	}
+
+	// docs is listed after its file, it is created by the file then updated
+	var docs *DirData
+	for _, c := range home.Children {
+		if c.Path == "/home/docs" {
+			docs = c
+		}
+	}
+	if docs == nil || !docs.IsDir || len(docs.Children) != 1 || docs.Children[0].Size != 20 {
+		t.Errorf("docs = %+v, want a directory with b.txt", docs)
+	}
+}
+
+func TestParseLsOutputInvalid(t *testing.T) {
+	_, err := parseLsOutput(strings.NewReader(`{"name":`), nil)
+	if _, ok := err.(*UnparseableOutputError); !ok {
+		t.Errorf("error = %v, want an *UnparseableOutputError", err)
+	}
+}
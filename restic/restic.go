@@ -1,20 +1,56 @@
 package restic
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/dustin/go-humanize"
 )
 
+// Snapshot represents an entry of `restic snapshots --json`.
 type Snapshot struct {
-	Id      string
-	Date    time.Time
-	Size    uint64
-	SizeStr string
-	Path    string
+	Id             string           `json:"id"`
+	ShortId        string           `json:"short_id"`
+	Date           time.Time        `json:"time"`
+	Parent         string           `json:"parent,omitempty"`
+	Tree           string           `json:"tree"`
+	Paths          []string         `json:"paths"`
+	Hostname       string           `json:"hostname"`
+	Username       string           `json:"username"`
+	Tags           []string         `json:"tags,omitempty"`
+	ProgramVersion string           `json:"program_version,omitempty"`
+	Summary        *SnapshotSummary `json:"summary,omitempty"`
+
+	// Not part of the restic output
+	Size    uint64 `json:"-"` // Total bytes processed, 0 if unknown
+	SizeStr string `json:"-"` // Human-readable size
+	Path    string `json:"-"` // Directory of the snapshot in the mount point
+}
+
+// SnapshotSummary holds the backup statistics restic stores
+// with a snapshot. Only written by restic 0.17 and newer.
+type SnapshotSummary struct {
+	BackupStart         time.Time `json:"backup_start"`
+	BackupEnd           time.Time `json:"backup_end"`
+	FilesNew            uint64    `json:"files_new"`
+	FilesChanged        uint64    `json:"files_changed"`
+	FilesUnmodified     uint64    `json:"files_unmodified"`
+	DirsNew             uint64    `json:"dirs_new"`
+	DirsChanged         uint64    `json:"dirs_changed"`
+	DirsUnmodified      uint64    `json:"dirs_unmodified"`
+	DataBlobs           int       `json:"data_blobs"`
+	TreeBlobs           int       `json:"tree_blobs"`
+	DataAdded           uint64    `json:"data_added"`
+	DataAddedPacked     uint64    `json:"data_added_packed"`
+	TotalFilesProcessed uint64    `json:"total_files_processed"`
+	TotalBytesProcessed uint64    `json:"total_bytes_processed"`
 }
 
 type SnapshotsMetadata struct {
@@ -22,30 +58,32 @@ type SnapshotsMetadata struct {
 	NewerId       string
 	OlderFullPath string
 	OlderId       string
+
+	// Used to read the content of the entries
+	Source SnapshotSource
+	Newer  Snapshot
+	Older  Snapshot
 }
 
 func (s Snapshot) String() string {
 	layout := "2006-01-02 15:04:05"
-	return fmt.Sprintf("%s\t%s\t%s", s.Id, s.Date.Format(layout), s.SizeStr)
+	return fmt.Sprintf("%s\t%s\t%s", s.ShortId, s.Date.Format(layout), s.SizeStr)
 }
 
+// GetSnapshots lists the snapshots of the repository. If mountPath is
+// not empty, each snapshot gets the path of its mount point directory.
 func GetSnapshots(repoPath, mountPath string) ([]Snapshot, error) {
 	var err error
 	if _, err := os.Stat(repoPath); err != nil {
-		return []Snapshot{}, fmt.Errorf("mount directory not found: %w", err)
+		return []Snapshot{}, fmt.Errorf("repository directory not found: %w", err)
 	}
 
-	args := []string{"-r", repoPath, "snapshots"}
+	args := []string{"-r", repoPath, "snapshots", "--json"}
 	var cmd *exec.Cmd
 	if cmd = exec.Command("restic", args...); cmd == nil {
 		return []Snapshot{}, fmt.Errorf("can't execute restic command: %w", err)
 	}
-	//key := "123"
-	//var stdin bytes.Buffer
-	//stdin.Write([]byte(key))
-	//cmd.Stdin = &stdin
 	cmd.Stdin = os.Stdin
-	// cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
 	output, err := cmd.Output()
@@ -56,9 +94,16 @@ func GetSnapshots(repoPath, mountPath string) ([]Snapshot, error) {
 	if err != nil {
 		return []Snapshot{}, fmt.Errorf("parsing command snapshot: %w", err)
 	}
-	snapshots, err = checkDirectoriesConsistency(snapshots, mountPath)
+	// Without a mount point the trees come from `restic ls`
+	if mountPath == "" {
+		return snapshots, nil
+	}
+	snapshots, skipped, err := checkDirectoriesConsistency(snapshots, mountPath)
 	if err != nil {
-		return []Snapshot{}, fmt.Errorf("directoy consistency error: %w", err)
+		return []Snapshot{}, fmt.Errorf("directory consistency error: %w", err)
+	}
+	for _, e := range skipped {
+		_, _ = fmt.Fprintf(os.Stderr, "Warning: skipping %v\n", e)
 	}
 
 	return snapshots, nil
@@ -67,92 +112,143 @@ func GetSnapshots(repoPath, mountPath string) ([]Snapshot, error) {
 
 func parseCmdSnapshots(rawOutput []byte) ([]Snapshot, error) {
 	var snapshots []Snapshot
+	if err := json.Unmarshal(rawOutput, &snapshots); err != nil {
+		return []Snapshot{}, &UnparseableOutputError{Command: "snapshots", Err: err}
+	}
 
-	// Split and remove header/footer
-	tokens := strings.Split(string(rawOutput), "\n")
-	start := 2
-	end := len(tokens) - 3
-
-	if end <= start {
-		errMsg := fmt.Errorf("expected at least 1 snapshot")
-		return []Snapshot{}, errMsg
-	}
-
-	for _, t := range tokens[start:end] {
-		fields := strings.Fields(t)
-		// TODO: remove hardcoded timezone
-		layout := "2006-01-02 15:04:05-07:00"
-		timeStr := fmt.Sprintf("%s %s%s",
-			fields[1],
-			fields[2],
-			"-03:00",
-		)
-		t, err := time.Parse(layout, timeStr)
-		if err != nil {
-			panic(err)
+	if len(snapshots) == 0 {
+		return []Snapshot{}, ErrNoSnapshots
+	}
+
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].Date.Before(snapshots[j].Date)
+	})
+
+	for i := range snapshots {
+		s := &snapshots[i]
+		// Older restic versions don't write the short id
+		if s.ShortId == "" && len(s.Id) >= 8 {
+			s.ShortId = s.Id[:8]
 		}
-		// Use the format: X.YYY Gib
-		sizeStr := fmt.Sprintf("%s%s",
-			fields[len(fields)-2],
-			fields[len(fields)-1],
-		)
-
-		s := Snapshot{
-			Id:      fields[0],
-			Date:    t,
-			Size:    uint64(123),
-			SizeStr: sizeStr,
+		s.SizeStr = "-"
+		if s.Summary != nil {
+			s.Size = s.Summary.TotalBytesProcessed
+			s.SizeStr = humanize.IBytes(s.Size)
 		}
-		snapshots = append(snapshots, s)
 	}
 
 	return snapshots, nil
 }
 
-func snapshotContainsTime(s []Snapshot, t time.Time) int {
-	for index, x := range s {
-		if x.Date.Compare(t) == 0 {
-			return index
+// mountKey identifies a snapshot directory in the mount point.
+// restic names each directory after the snapshot time and appends
+// "-1", "-2"... to the ones taken in the same second. The unix time
+// makes the key independent of the time zone used in the name.
+type mountKey struct {
+	unix int64
+	seq  int
+}
+
+// parseMountName returns the key of a directory named by restic mount.
+func parseMountName(name string) (mountKey, error) {
+	t, err := time.Parse(time.RFC3339, name)
+	if err == nil {
+		return mountKey{unix: t.Unix()}, nil
+	}
+
+	// Try again without the "-N" suffix
+	i := strings.LastIndex(name, "-")
+	if i == -1 {
+		return mountKey{}, err
+	}
+	seq, seqErr := strconv.Atoi(name[i+1:])
+	if seqErr != nil || seq < 1 {
+		return mountKey{}, err
+	}
+	t, err = time.Parse(time.RFC3339, name[:i])
+	if err != nil {
+		return mountKey{}, err
+	}
+	return mountKey{unix: t.Unix(), seq: seq}, nil
+}
+
+// snapshotKeys returns the mount key of each snapshot, in the same order.
+// Snapshots in the same second are numbered by time, like restic does.
+func snapshotKeys(s []Snapshot) []mountKey {
+	order := make([]int, len(s))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := s[order[i]], s[order[j]]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
 		}
+		return a.Id < b.Id
+	})
+
+	keys := make([]mountKey, len(s))
+	seen := make(map[int64]int)
+	for _, index := range order {
+		unix := s[index].Date.Unix()
+		keys[index] = mountKey{unix: unix, seq: seen[unix]}
+		seen[unix]++
 	}
-	return -1
+	return keys
 }
 
 // Checks if the output of `restic snapshots` has a directory
 // associated with each entry. It compares the time for the
-// command output with the filename in the snapshots directory
-func checkDirectoriesConsistency(s []Snapshot, mountPath string) ([]Snapshot, error) {
+// command output with the filename in the snapshots directory.
+// Entries not created by restic and snapshots without a directory
+// are skipped and returned
+func checkDirectoriesConsistency(s []Snapshot, mountPath string) ([]Snapshot, []error, error) {
 	mountPath = path.Join(mountPath, "snapshots")
-	if _, err := os.Stat(mountPath); err != nil {
-		return []Snapshot{}, fmt.Errorf("mount directory not found: %w", err)
-	}
-
-	dateTimeLayout := "2006-01-02T15:04:05-07:00"
-
 	dirEntries, err := os.ReadDir(mountPath)
 	if err != nil {
-		errMsg := fmt.Errorf("directory missing or not mounted: %w", err)
-		return []Snapshot{}, errMsg
+		return []Snapshot{}, nil, &MountNotPresentError{Path: mountPath, Err: err}
 	}
+
+	indexes := make(map[mountKey]int)
+	for index, key := range snapshotKeys(s) {
+		indexes[key] = index
+	}
+
+	var skipped []error
 	for _, entry := range dirEntries {
-		t, err := time.Parse(dateTimeLayout, entry.Name())
 		// The directory has a symlink to the most recent snapshot. We ignore it
 		if entry.Name() == "latest" {
 			continue
 		}
-		// Bad naming and it is not the previous case. It should never happen.
+		key, err := parseMountName(entry.Name())
 		if err != nil {
-			panic(err)
+			skipped = append(skipped, &UnknownMountEntryError{Name: entry.Name(), Err: err})
+			continue
 		}
 
-		index := snapshotContainsTime(s, t)
-		if index == -1 {
-			errMsg := fmt.Errorf("mismatch entries for snapshot %s", entry.Name())
-			return []Snapshot{}, errMsg
+		index, ok := indexes[key]
+		if !ok {
+			skipped = append(skipped, &UnknownMountEntryError{Name: entry.Name()})
+			continue
 		}
 		s[index].Path = path.Join(mountPath, entry.Name())
+	}
 
+	var found, missing []Snapshot
+	for _, x := range s {
+		if x.Path == "" {
+			missing = append(missing, x)
+		} else {
+			found = append(found, x)
+		}
+	}
+	if len(missing) > 0 {
+		// The mount point has none of the snapshots
+		if len(found) == 0 {
+			return []Snapshot{}, skipped, &SnapshotWithoutFolderError{Snapshots: missing}
+		}
+		skipped = append(skipped, &SnapshotWithoutFolderError{Snapshots: missing})
 	}
 
-	return s, nil
+	return found, skipped, nil
 }
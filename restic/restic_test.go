@@ -0,0 +1,92 @@
+package restic
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseMountName(t *testing.T) {
+	unix := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).Unix()
+	tests := []struct {
+		name    string
+		want    mountKey
+		wantErr bool
+	}{
+		{name: "2024-01-02T03:04:05Z", want: mountKey{unix: unix}},
+		{name: "2024-01-02T04:04:05+01:00", want: mountKey{unix: unix}},
+		{name: "2024-01-02T03:04:05Z-2", want: mountKey{unix: unix, seq: 2}},
+		{name: "2024-01-02T04:04:05+01:00-1", want: mountKey{unix: unix, seq: 1}},
+		{name: "2024-01-02T03:04:05Z-0", wantErr: true},
+		{name: "2024-01-02T03:04:05Z-x", wantErr: true},
+		{name: "latest", wantErr: true},
+		{name: "notes-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseMountName(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseMountName(%q) error = %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseMountName(%q) = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSnapshotKeys(t *testing.T) {
+	second := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	snapshots := []Snapshot{
+		{Id: "c", Date: second.Add(500 * time.Millisecond)},
+		{Id: "later", Date: second.Add(time.Hour)},
+		{Id: "b", Date: second},
+		{Id: "a", Date: second},
+	}
+	want := []mountKey{
+		{unix: second.Unix(), seq: 2},
+		{unix: second.Add(time.Hour).Unix()},
+		{unix: second.Unix(), seq: 1},
+		{unix: second.Unix()},
+	}
+
+	got := snapshotKeys(snapshots)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("key of %s = %+v, want %+v", snapshots[i].Id, got[i], want[i])
+		}
+	}
+}
+
+func TestCheckDirectoriesConsistency(t *testing.T) {
+	day := func(n int) time.Time { return time.Date(2024, 1, n, 3, 4, 5, 0, time.UTC) }
+	snapshots := []Snapshot{
+		{Id: "a", ShortId: "a", Date: day(1)},
+		{Id: "b", ShortId: "b", Date: day(2)},
+		{Id: "c", ShortId: "c", Date: day(3)},
+	}
+	mountPath := t.TempDir()
+	for _, name := range []string{"2024-01-01T03:04:05Z", "2024-01-02T04:04:05+01:00", "2024-01-09T03:04:05Z", "notes", "latest"} {
+		if err := os.MkdirAll(filepath.Join(mountPath, "snapshots", name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	found, skipped, err := checkDirectoriesConsistency(snapshots, mountPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 || found[0].Id != "a" || found[1].Path != filepath.Join(mountPath, "snapshots", "2024-01-02T04:04:05+01:00") {
+		t.Errorf("snapshots = %+v, want a and b with their directories", found)
+	}
+	var unknown *UnknownMountEntryError
+	var missing *SnapshotWithoutFolderError
+	if len(skipped) != 3 || !errors.As(skipped[0], &unknown) || !errors.As(skipped[2], &missing) || missing.Snapshots[0].Id != "c" {
+		t.Errorf("skipped = %v, want the unknown entries and c", skipped)
+	}
+
+	if _, _, err := checkDirectoriesConsistency(snapshots[2:], mountPath); !errors.As(err, &missing) {
+		t.Errorf("error = %v, want a *SnapshotWithoutFolderError", err)
+	}
+}
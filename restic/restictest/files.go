@@ -0,0 +1,30 @@
+// Package restictest writes the files of fixture snapshots, read back
+// by tests through restic.DirSource
+package restictest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// ModTime is the modification time of the written files
+var ModTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// WriteFiles creates the files under root, by path relative to root
+func WriteFiles(t testing.TB, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, ModTime, ModTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package restic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Conflict is what a restore does with the files that already exist
+type Conflict string
+
+const (
+	ConflictSkip      Conflict = "skip"
+	ConflictOverwrite Conflict = "overwrite"
+	ConflictRename    Conflict = "rename" // Restore next to the existing file
+)
+
+// Next returns the policy after c, wrapping to the first one
+func (c Conflict) Next() Conflict {
+	switch c {
+	case ConflictSkip:
+		return ConflictOverwrite
+	case ConflictOverwrite:
+		return ConflictRename
+	default:
+		return ConflictSkip
+	}
+}
+
+// RestorePlan is what restoring an entry to a target would do
+type RestorePlan struct {
+	Files    int64
+	Dirs     int64
+	Bytes    int64
+	Existing int64 // Files that already exist in the target
+}
+
+// RestoreProgress is the state of a running restore
+type RestoreProgress struct {
+	Files      int64
+	TotalFiles int64
+	Bytes      int64
+	TotalBytes int64
+}
+
+// RestoreResult summarizes a finished restore
+type RestoreResult struct {
+	Restored int64
+	Skipped  int64
+	Renamed  int64
+	Bytes    int64
+}
+
+// PlanRestore returns what restoring d to target would do, without
+// writing anything
+func PlanRestore(d *DirData, target string) RestorePlan {
+	var plan RestorePlan
+	var walk func(e *DirData, dst string)
+	walk = func(e *DirData, dst string) {
+		if e.IsDir {
+			plan.Dirs++
+			for _, c := range e.Children {
+				walk(c, filepath.Join(dst, path.Base(c.Path)))
+			}
+			return
+		}
+		plan.Files++
+		plan.Bytes += e.Size
+		if _, err := os.Lstat(dst); err == nil {
+			plan.Existing++
+		}
+	}
+	walk(d, target)
+	return plan
+}
+
+// restorer copies or moves a tree of files to a target
+type restorer struct {
+	ctx      context.Context
+	conflict Conflict
+	move     bool // Rename the files instead of copying them
+	progress func(RestoreProgress)
+	state    RestoreProgress
+	result   RestoreResult
+}
+
+func newRestorer(ctx context.Context, d *DirData, conflict Conflict, move bool, progress func(RestoreProgress)) *restorer {
+	return &restorer{
+		ctx:      ctx,
+		conflict: conflict,
+		move:     move,
+		progress: progress,
+		state:    RestoreProgress{TotalFiles: d.Files, TotalBytes: d.Size},
+	}
+}
+
+// restore writes the entry d, found at src, to dst
+func (r *restorer) restore(d *DirData, src, dst string) error {
+	if d.IsDir {
+		if err := os.MkdirAll(dst, d.Mode.Perm()|0o700); err != nil {
+			return fmt.Errorf("can't create directory: %w", err)
+		}
+		for _, c := range d.Children {
+			name := path.Base(c.Path)
+			if err := r.restore(c, filepath.Join(src, name), filepath.Join(dst, name)); err != nil {
+				return err
+			}
+		}
+		if !d.ModTime.IsZero() {
+			_ = os.Chtimes(dst, d.ModTime, d.ModTime)
+		}
+		return nil
+	}
+
+	if err := r.ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		switch r.conflict {
+		case ConflictSkip:
+			r.result.Skipped++
+			r.advance(d.Size)
+			return nil
+		case ConflictRename:
+			dst = freeName(dst)
+			r.result.Renamed++
+		default:
+			if err := os.Remove(dst); err != nil {
+				return fmt.Errorf("can't overwrite %s: %w", dst, err)
+			}
+		}
+	}
+
+	if err := r.writeFile(d, src, dst); err != nil {
+		return err
+	}
+	r.result.Restored++
+	r.result.Bytes += d.Size
+	r.advance(d.Size)
+	return nil
+}
+
+func (r *restorer) advance(size int64) {
+	r.state.Files++
+	r.state.Bytes += size
+	if r.progress != nil {
+		r.progress(r.state)
+	}
+}
+
+func (r *restorer) writeFile(d *DirData, src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return fmt.Errorf("can't read %s: %w", src, err)
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		link, err := os.Readlink(src)
+		if err != nil {
+			return fmt.Errorf("can't read link %s: %w", src, err)
+		}
+		return os.Symlink(link, dst)
+	}
+	if r.move {
+		if err := os.Rename(src, dst); err == nil {
+			return nil
+		}
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("can't read %s: %w", src, err)
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("can't create %s: %w", dst, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return fmt.Errorf("can't copy %s: %w", src, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("can't write %s: %w", dst, err)
+	}
+	_ = os.Chtimes(dst, info.ModTime(), info.ModTime())
+	return nil
+}
+
+// freeName returns the first of "name.restored", "name.restored-2"...
+// that doesn't exist
+func freeName(name string) string {
+	candidate := name + ".restored"
+	for i := 2; ; i++ {
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s.restored-%d", name, i)
+	}
+}
+
+// CopyEntry restores d by copying it from a mount point to target. It
+// stops with ctx.Err() before the next file when ctx is cancelled.
+func CopyEntry(ctx context.Context, d *DirData, target string, conflict Conflict, progress func(RestoreProgress)) (RestoreResult, error) {
+	r := newRestorer(ctx, d, conflict, false, progress)
+	err := r.restore(d, d.Path, target)
+	return r.result, err
+}
+
+// restoreStatus is a status line of `restic restore --json`
+type restoreStatus struct {
+	MessageType   string `json:"message_type"`
+	TotalFiles    int64  `json:"total_files"`
+	FilesRestored int64  `json:"files_restored"`
+	TotalBytes    int64  `json:"total_bytes"`
+	BytesRestored int64  `json:"bytes_restored"`
+}
+
+// RestoreEntry restores d with `restic restore --include` to a
+// temporary directory next to target, then moves the files to target.
+// The progress of restic is reported until the files are moved. restic
+// is killed when ctx is cancelled.
+func RestoreEntry(ctx context.Context, repoPath, snapshotId string, d *DirData, target string, conflict Conflict, progress func(RestoreProgress)) (RestoreResult, error) {
+	parent := filepath.Dir(target)
+	if err := os.MkdirAll(parent, 0o755); err != nil {
+		return RestoreResult{}, fmt.Errorf("can't create directory: %w", err)
+	}
+	tmp, err := os.MkdirTemp(parent, ".gestic-restore-")
+	if err != nil {
+		return RestoreResult{}, fmt.Errorf("can't create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	// Restoring the parent directory with a single entry keeps the
+	// entry at the top of the temporary directory
+	args := []string{"-r", repoPath, "restore", "--json",
+		snapshotId + ":" + path.Dir(d.Path),
+		"--include", "/" + path.Base(d.Path),
+		"--target", tmp,
+	}
+	cmd := exec.CommandContext(ctx, "restic", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return RestoreResult{}, fmt.Errorf("can't execute restic command: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return RestoreResult{}, fmt.Errorf("can't execute restic command: %w", err)
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		var status restoreStatus
+		if json.Unmarshal(scanner.Bytes(), &status) != nil || status.MessageType != "status" {
+			continue
+		}
+		if progress != nil {
+			progress(RestoreProgress{
+				Files:      status.FilesRestored,
+				TotalFiles: status.TotalFiles,
+				Bytes:      status.BytesRestored,
+				TotalBytes: status.TotalBytes,
+			})
+		}
+	}
+	_, _ = io.Copy(io.Discard, stdout)
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return RestoreResult{}, ctx.Err()
+		}
+		return RestoreResult{}, fmt.Errorf("error return from restic command: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	r := newRestorer(ctx, d, conflict, true, progress)
+	err = r.restore(d, filepath.Join(tmp, path.Base(d.Path)), target)
+	return r.result, err
+}
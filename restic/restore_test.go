@@ -0,0 +1,82 @@
+package restic
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gestic/restic/restictest"
+)
+
+func TestCopyEntry(t *testing.T) {
+	root := t.TempDir()
+	restictest.WriteFiles(t, root, map[string]string{
+		"s1/dir/a.txt":   "new a",
+		"s1/dir/sub/b":   "new b",
+		"target/a.txt":   "old a",
+		"target/keep.md": "keep",
+	})
+	src := DirSource{Root: root}
+	tree, err := GetDirEntries(context.Background(), filepath.Join(root, "s1"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := tree.Children[0]
+	target := filepath.Join(root, "target")
+
+	plan := PlanRestore(dir, target)
+	if want := (RestorePlan{Files: 2, Dirs: 2, Bytes: 10, Existing: 1}); plan != want {
+		t.Errorf("plan = %+v, want %+v", plan, want)
+	}
+
+	tests := []struct {
+		conflict Conflict
+		want     RestoreResult
+		a        string // Content of target/a.txt
+	}{
+		{ConflictSkip, RestoreResult{Restored: 1, Skipped: 1, Bytes: 5}, "old a"},
+		{ConflictRename, RestoreResult{Restored: 2, Renamed: 1, Bytes: 10}, "old a"},
+		{ConflictOverwrite, RestoreResult{Restored: 2, Bytes: 10}, "new a"},
+	}
+	for _, tt := range tests {
+		_ = os.RemoveAll(filepath.Join(target, "sub"))
+		var last RestoreProgress
+		result, err := src.Restore(context.Background(), Snapshot{}, dir, target, tt.conflict, func(p RestoreProgress) { last = p })
+		if err != nil {
+			t.Fatalf("%s: %v", tt.conflict, err)
+		}
+		if result != tt.want {
+			t.Errorf("%s: result = %+v, want %+v", tt.conflict, result, tt.want)
+		}
+		if want := (RestoreProgress{Files: 2, TotalFiles: 2, Bytes: 10, TotalBytes: 10}); last != want {
+			t.Errorf("%s: progress = %+v, want %+v", tt.conflict, last, want)
+		}
+		if a, _ := os.ReadFile(filepath.Join(target, "a.txt")); string(a) != tt.a {
+			t.Errorf("%s: a.txt = %q, want %q", tt.conflict, a, tt.a)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(target, "a.txt.restored")); err != nil {
+		t.Errorf("the renamed file is missing: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(target, "keep.md")); err != nil {
+		t.Errorf("a file of the target was removed: %v", err)
+	}
+}
+
+func TestCopyEntryCancelled(t *testing.T) {
+	root := t.TempDir()
+	restictest.WriteFiles(t, root, map[string]string{"s1/a": "a", "s1/b": "b"})
+	tree, err := GetDirEntries(context.Background(), filepath.Join(root, "s1"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err := CopyEntry(ctx, tree, filepath.Join(root, "target"), ConflictSkip, nil)
+	if !errors.Is(err, context.Canceled) || result.Restored != 0 {
+		t.Errorf("result = %+v, %v, want nothing restored and context.Canceled", result, err)
+	}
+}
@@ -0,0 +1,129 @@
+package restic
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// SnapshotSource lists the snapshots of a repository and reads their trees.
+type SnapshotSource interface {
+	// Snapshots returns the snapshots sorted from oldest to newest
+	Snapshots() ([]Snapshot, error)
+	// Tree returns the directory tree of a snapshot, reporting the
+	// entries read to progress if not nil. It stops when ctx is cancelled.
+	Tree(ctx context.Context, s Snapshot, progress func(TreeProgress)) (*DirData, error)
+	// Open returns the content of a file of a snapshot, by its DirData path
+	Open(s Snapshot, path string) (io.ReadCloser, error)
+	// Restore writes the entry d of a snapshot to target. It stops
+	// between files when ctx is cancelled.
+	Restore(ctx context.Context, s Snapshot, d *DirData, target string, conflict Conflict, progress func(RestoreProgress)) (RestoreResult, error)
+}
+
+// MountSource reads the trees from a restic mount point.
+type MountSource struct {
+	RepoPath  string
+	MountPath string
+}
+
+func (src MountSource) Snapshots() ([]Snapshot, error) {
+	return GetSnapshots(src.RepoPath, src.MountPath)
+}
+
+func (src MountSource) Tree(ctx context.Context, s Snapshot, progress func(TreeProgress)) (*DirData, error) {
+	return GetDirEntries(ctx, s.Path, progress)
+}
+
+func (src MountSource) Open(s Snapshot, path string) (io.ReadCloser, error) {
+	return os.Open(path)
+}
+
+func (src MountSource) Restore(ctx context.Context, s Snapshot, d *DirData, target string, conflict Conflict, progress func(RestoreProgress)) (RestoreResult, error) {
+	return CopyEntry(ctx, d, target, conflict, progress)
+}
+
+// LsSource reads the trees with `restic ls --json`, without a mount point.
+type LsSource struct {
+	RepoPath string
+}
+
+func (src LsSource) Snapshots() ([]Snapshot, error) {
+	return GetSnapshots(src.RepoPath, "")
+}
+
+func (src LsSource) Tree(ctx context.Context, s Snapshot, progress func(TreeProgress)) (*DirData, error) {
+	return GetLsEntries(ctx, src.RepoPath, s.Id, progress)
+}
+
+func (src LsSource) Open(s Snapshot, path string) (io.ReadCloser, error) {
+	return DumpFile(src.RepoPath, s.Id, path)
+}
+
+func (src LsSource) Restore(ctx context.Context, s Snapshot, d *DirData, target string, conflict Conflict, progress func(RestoreProgress)) (RestoreResult, error) {
+	return RestoreEntry(ctx, src.RepoPath, s.Id, d, target, conflict, progress)
+}
+
+// DirSource treats each directory in Root as a snapshot. Directories
+// named like in a restic mount point use that time, the others use
+// their modification time. It doesn't need restic, e.g. for tests.
+type DirSource struct {
+	Root string
+}
+
+func (src DirSource) Snapshots() ([]Snapshot, error) {
+	entries, err := os.ReadDir(src.Root)
+	if err != nil {
+		return []Snapshot{}, &MountNotPresentError{Path: src.Root, Err: err}
+	}
+
+	var snapshots []Snapshot
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return []Snapshot{}, fmt.Errorf("can't read snapshot directory: %w", err)
+		}
+		date := info.ModTime()
+		if key, err := parseMountName(entry.Name()); err == nil {
+			date = time.Unix(key.unix, 0)
+		}
+
+		shortId := entry.Name()
+		if len(shortId) > 8 {
+			shortId = shortId[:8]
+		}
+		snapshots = append(snapshots, Snapshot{
+			Id:      entry.Name(),
+			ShortId: shortId,
+			Date:    date,
+			SizeStr: "-",
+			Path:    filepath.Join(src.Root, entry.Name()),
+		})
+	}
+	if len(snapshots) == 0 {
+		return []Snapshot{}, ErrNoSnapshots
+	}
+
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].Date.Before(snapshots[j].Date)
+	})
+	return snapshots, nil
+}
+
+func (src DirSource) Tree(ctx context.Context, s Snapshot, progress func(TreeProgress)) (*DirData, error) {
+	return GetDirEntries(ctx, s.Path, progress)
+}
+
+func (src DirSource) Open(s Snapshot, path string) (io.ReadCloser, error) {
+	return os.Open(path)
+}
+
+func (src DirSource) Restore(ctx context.Context, s Snapshot, d *DirData, target string, conflict Conflict, progress func(RestoreProgress)) (RestoreResult, error) {
+	return CopyEntry(ctx, d, target, conflict, progress)
+}
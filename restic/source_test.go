@@ -0,0 +1,79 @@
+package restic
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"gestic/restic/restictest"
+)
+
+func TestDirSourceSnapshots(t *testing.T) {
+	root := t.TempDir()
+	restictest.WriteFiles(t, root, map[string]string{
+		"2024-03-01T10:00:00Z/a":   "a",
+		"2024-02-01T10:00:00Z/a":   "a",
+		"2024-02-01T10:00:00Z-1/a": "a",
+		"not-a-snapshot.txt":       "",
+	})
+
+	snapshots, err := DirSource{Root: root}.Snapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, s := range snapshots {
+		ids = append(ids, s.Id)
+	}
+	want := []string{"2024-02-01T10:00:00Z", "2024-02-01T10:00:00Z-1", "2024-03-01T10:00:00Z"}
+	if len(ids) != len(want) {
+		t.Fatalf("snapshots = %v, want %v", ids, want)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("snapshots = %v, want %v", ids, want)
+		}
+	}
+	if s := snapshots[2]; s.ShortId != "2024-03-" || s.Path != filepath.Join(root, want[2]) {
+		t.Errorf("snapshot = %+v, want the short ID 2024-03- and its directory", s)
+	}
+}
+
+func TestDirSourceSnapshotsEmpty(t *testing.T) {
+	if _, err := (DirSource{Root: t.TempDir()}).Snapshots(); !errors.Is(err, ErrNoSnapshots) {
+		t.Errorf("error = %v, want ErrNoSnapshots", err)
+	}
+	var mountErr *MountNotPresentError
+	if _, err := (DirSource{Root: filepath.Join(t.TempDir(), "missing")}).Snapshots(); !errors.As(err, &mountErr) {
+		t.Errorf("error = %v, want a *MountNotPresentError", err)
+	}
+}
+
+func TestDirSourceTree(t *testing.T) {
+	root := t.TempDir()
+	restictest.WriteFiles(t, root, map[string]string{
+		"s1/a.txt":     "12345",
+		"s1/dir/b.txt": "123",
+		"s1/dir/c/d":   "1",
+	})
+	src := DirSource{Root: root}
+	snapshots, err := src.Snapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree, err := src.Tree(context.Background(), snapshots[0], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.Size != 9 || tree.Files != 3 || tree.Dirs != 2 {
+		t.Errorf("tree totals = %d bytes, %d files, %d dirs, want 9, 3, 2", tree.Size, tree.Files, tree.Dirs)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := src.Tree(ctx, snapshots[0], nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+}